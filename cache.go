@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var lastCollectionDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "exporter", "last_collection_timestamp_seconds"),
+	"Unix time of the last successful background run of a collector.",
+	[]string{"collector"}, nil,
+)
+
+// snapshot holds what a background collector produced.
+type snapshot struct {
+	metrics   []prometheus.Metric
+	timestamp time.Time
+	duration  time.Duration
+	success   bool
+}
+
+// snapshotCache keeps the last good snapshot of every collector that is
+// refreshed in the background, so scrapes never touch Harbor themselves.
+type snapshotCache struct {
+	mtx       sync.RWMutex
+	snapshots map[string]*snapshot
+}
+
+func newSnapshotCache() *snapshotCache {
+	return &snapshotCache{snapshots: make(map[string]*snapshot)}
+}
+
+// refresh runs the collector once and stores the result. The metrics of a
+// failed run are discarded so the previous good snapshot keeps being served.
+func (s *snapshotCache) refresh(ctx context.Context, name string, timeout time.Duration, c Collector, logger log.Logger) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+	duration, err := runCollector(ctx, name, timeout, c, ch, logger)
+	close(ch)
+	metrics := <-done
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	snap, ok := s.snapshots[name]
+	if !ok {
+		snap = &snapshot{}
+		s.snapshots[name] = snap
+	}
+	snap.duration = duration
+	snap.success = err == nil
+	if err == nil {
+		snap.metrics = metrics
+		snap.timestamp = time.Now()
+	}
+}
+
+// collect sends the cached snapshot of the named collector and reports
+// whether its last run succeeded.
+func (s *snapshotCache) collect(name string, ch chan<- prometheus.Metric) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	snap, ok := s.snapshots[name]
+	if !ok {
+		// Not run yet.
+		reportScrape(ch, name, 0, false)
+		return false
+	}
+	for _, m := range snap.metrics {
+		ch <- m
+	}
+	reportScrape(ch, name, snap.duration, snap.success)
+	if !snap.timestamp.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			lastCollectionDesc, prometheus.GaugeValue, float64(snap.timestamp.UnixNano())/1e9, name,
+		)
+	}
+	return snap.success
+}
+
+// startCollectionLoop refreshes every collector with a background interval
+// on its own schedule until ctx is cancelled.
+func (e *Exporter) startCollectionLoop(ctx context.Context) {
+	for name, c := range e.collectors {
+		settings := e.settings[name]
+		if settings.interval <= 0 {
+			continue
+		}
+		level.Info(e.logger).Log("msg", "Collecting in the background", "collector", name, "interval", settings.interval)
+		go func(name string, c Collector, settings collectorSettings) {
+			ticker := time.NewTicker(settings.interval)
+			defer ticker.Stop()
+			for {
+				e.cache.refresh(ctx, name, settings.timeout, c, e.logger)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(name, c, settings)
+	}
+}
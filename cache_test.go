@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cacheTest collects the snapshot of the test collector from s.
+func cacheTest(t *testing.T, s *snapshotCache) (bool, []sample) {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	ok := s.collect("test", ch)
+	close(ch)
+	var samples []sample
+	for m := range ch {
+		samples = append(samples, toSample(t, m))
+	}
+	return ok, samples
+}
+
+func TestSnapshotCacheNotRun(t *testing.T) {
+	ok, samples := cacheTest(t, newSnapshotCache())
+	if ok {
+		t.Error("collector succeeded before its first run")
+	}
+	checkScrape(t, samples, 0, 0)
+	if _, found := find(samples, "harbor_exporter_last_collection_timestamp_seconds"); found {
+		t.Error("last collection exported before the first run")
+	}
+}
+
+// A failed or timed out run keeps serving the samples and the timestamp of
+// the last good one.
+func TestSnapshotCacheKeepsLastGood(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		fail func(ctx context.Context, ch chan<- prometheus.Metric) error
+	}{
+		{
+			name: "error",
+			fail: func(ctx context.Context, ch chan<- prometheus.Metric) error {
+				ch <- testMetric("bad")
+				return errors.New("boom")
+			},
+		},
+		{
+			name: "timeout",
+			fail: func(ctx context.Context, ch chan<- prometheus.Metric) error {
+				ch <- testMetric("bad")
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runs := 0
+			c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+				runs++
+				if runs > 1 {
+					return test.fail(ctx, ch)
+				}
+				ch <- testMetric("good")
+				return nil
+			})
+			s := newSnapshotCache()
+
+			s.refresh(context.Background(), "test", time.Second, c, log.NewNopLogger())
+			ok, samples := cacheTest(t, s)
+			if !ok {
+				t.Fatal("first run failed")
+			}
+			checkScrape(t, samples, 1, time.Second)
+			first, found := find(samples, "harbor_exporter_last_collection_timestamp_seconds", "collector", "test")
+			if !found {
+				t.Fatal("last collection missing after a good run")
+			}
+
+			s.refresh(context.Background(), "test", 50*time.Millisecond, c, log.NewNopLogger())
+			ok, samples = cacheTest(t, s)
+			if ok {
+				t.Error("failed run reported as success")
+			}
+			checkScrape(t, samples, 0, time.Second)
+			if _, found := find(samples, "test_metric", "step", "good"); !found {
+				t.Error("samples of the good run are gone")
+			}
+			if _, found := find(samples, "test_metric", "step", "bad"); found {
+				t.Error("samples of the failed run are served")
+			}
+			if v, _ := find(samples, "harbor_exporter_last_collection_timestamp_seconds", "collector", "test"); v != first {
+				t.Errorf("last collection = %v, want %v of the good run", v, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	defaultEnabled  = true
+	defaultDisabled = false
+)
+
+var (
+	factories         = make(map[string]func(e *Exporter) (Collector, error))
+	collectorState    = make(map[string]*bool)
+	collectorTimeout  = make(map[string]*time.Duration)
+	collectorInterval = make(map[string]*time.Duration)
+
+	defaultCollectorTimeout = kingpin.Flag("collector.timeout", "Default deadline for a single collector run, overridable per collector with --collector.<name>.timeout.").Default("10s").Duration()
+	collectionInterval      = kingpin.Flag("collection.interval", "Refresh collectors in the background at this interval and serve the last good snapshot on scrape, 0 to collect on every scrape. Overridable per collector with --collector.<name>.interval.").Default("0s").Duration()
+
+	// jobStatuses are the status label values of Harbor jobs.
+	jobStatuses = []string{"pending", "scheduled", "running", "stopped", "error", "success"}
+
+	scrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "collector_duration_seconds"),
+		"Duration of a collector scrape.",
+		[]string{"collector"}, nil,
+	)
+	scrapeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "collector_success"),
+		"Whether a collector succeeded.",
+		[]string{"collector"}, nil,
+	)
+)
+
+// unavailableError is returned by a collector factory when what the collector
+// needs is missing in this deployment.
+type unavailableError struct {
+	reason string
+}
+
+func (e *unavailableError) Error() string {
+	return e.reason
+}
+
+// isUnavailable reports whether err means the collector can not run here,
+// either for lack of a Harbor feature or of its environment, and should be
+// skipped.
+func isUnavailable(err error) bool {
+	_, ok := err.(*unavailableError)
+	return ok || harbor.IsUnsupported(err)
+}
+
+// Collector is the interface a collector has to implement.
+type Collector interface {
+	// Update gets new metrics and exposes them via the prometheus channel.
+	// Implementations must give up once ctx is done.
+	Update(ctx context.Context, ch chan<- prometheus.Metric) error
+}
+
+// registerCollector makes a collector known to the exporter and adds the
+// --collector.<name> / --no-collector.<name>, --collector.<name>.timeout and
+// --collector.<name>.interval flags for it. It must be called from init so
+// the flags exist before kingpin parses the command line.
+func registerCollector(name string, isDefaultEnabled bool, factory func(e *Exporter) (Collector, error)) {
+	helpDefaultState := "disabled"
+	if isDefaultEnabled {
+		helpDefaultState = "enabled"
+	}
+
+	flagName := fmt.Sprintf("collector.%s", name)
+	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", name, helpDefaultState)
+	defaultValue := strconv.FormatBool(isDefaultEnabled)
+
+	collectorState[name] = kingpin.Flag(flagName, flagHelp).Default(defaultValue).Bool()
+	collectorTimeout[name] = kingpin.Flag(flagName+".timeout", fmt.Sprintf("Deadline for the %s collector, 0 to use --collector.timeout.", name)).Default("0s").Duration()
+	collectorInterval[name] = kingpin.Flag(flagName+".interval", fmt.Sprintf("Background refresh interval for the %s collector, 0 to use --collection.interval.", name)).Default("0s").Duration()
+	factories[name] = factory
+}
+
+// collectorSettings is the resolved configuration of a single collector.
+// The fields below interval are the options of single collectors, see
+// CollectorConfig.
+type collectorSettings struct {
+	timeout  time.Duration
+	interval time.Duration
+
+	expiryWindow time.Duration
+	expected     []string
+	window       time.Duration
+	history      int
+	source       string
+	level        string
+}
+
+// settingsFor merges the flags and the config file for the named collector.
+// Per-collector values win over the defaults, and within each level the
+// config file wins over the flags.
+func settingsFor(name string, conf *Config) collectorSettings {
+	cc := conf.Collectors[name]
+	s := collectorSettings{
+		timeout:      firstPositive(cc.Timeout, *collectorTimeout[name], conf.Collection.Timeout, *defaultCollectorTimeout),
+		interval:     firstPositive(cc.Interval, *collectorInterval[name], conf.Collection.Interval, *collectionInterval),
+		expiryWindow: firstPositive(cc.ExpiryWindow, *robotsExpiryWindow),
+		expected:     *schedulesExpected,
+		window:       firstPositive(cc.Window, *proxyCacheWindow),
+		history:      *replicationsHistory,
+		source:       *vulnerabilitiesSource,
+		level:        *vulnerabilitiesLevel,
+	}
+	if cc.Expected != nil {
+		s.expected = cc.Expected
+	}
+	if cc.History > 0 {
+		s.history = cc.History
+	}
+	if cc.Source != "" {
+		s.source = cc.Source
+	}
+	if cc.Level != "" {
+		s.level = cc.Level
+	}
+	return s
+}
+
+// enabledCollectors returns the settings of every collector switched on by
+// the flags or the config file.
+func enabledCollectors(conf *Config) map[string]collectorSettings {
+	enabled := make(map[string]collectorSettings)
+	for name, state := range collectorState {
+		on := *state
+		if cc, ok := conf.Collectors[name]; ok && cc.Enabled != nil {
+			on = *cc.Enabled
+		}
+		if on {
+			enabled[name] = settingsFor(name, conf)
+		}
+	}
+	return enabled
+}
+
+func firstPositive(durations ...time.Duration) time.Duration {
+	for _, d := range durations {
+		if d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// runCollector runs a single collector under its own deadline and forwards
+// its metrics to ch. Metrics sent before the deadline are kept, a collector
+// that overruns it is abandoned, and a panic is recovered and returned as an
+// error so that the other collectors still make it into the scrape.
+func runCollector(ctx context.Context, name string, timeout time.Duration, c Collector, ch chan<- prometheus.Metric, logger log.Logger) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	metrics := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	begin := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				level.Error(logger).Log("msg", "collector panicked", "name", name, "panic", r, "stack", string(debug.Stack()))
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.Update(ctx, metrics)
+	}()
+
+	var err error
+collect:
+	for {
+		select {
+		case m := <-metrics:
+			ch <- m
+		case err = <-done:
+			break collect
+		case <-ctx.Done():
+			err = ctx.Err()
+			// Keep draining so the abandoned Update does not block forever.
+			go func() {
+				for {
+					select {
+					case <-metrics:
+					case <-done:
+						return
+					}
+				}
+			}()
+			break collect
+		}
+	}
+	duration := time.Since(begin)
+
+	if err != nil {
+		level.Error(logger).Log("msg", "collector failed", "name", name, "duration_seconds", duration.Seconds(), "err", err)
+	} else {
+		level.Debug(logger).Log("msg", "collector succeeded", "name", name, "duration_seconds", duration.Seconds())
+	}
+	return duration, err
+}
+
+// execute runs a single collector and reports its duration and success.
+func execute(ctx context.Context, name string, timeout time.Duration, c Collector, ch chan<- prometheus.Metric, logger log.Logger) bool {
+	duration, err := runCollector(ctx, name, timeout, c, ch, logger)
+	reportScrape(ch, name, duration, err == nil)
+	return err == nil
+}
+
+func reportScrape(ch chan<- prometheus.Metric, name string, duration time.Duration, ok bool) {
+	var success float64
+	if ok {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+}
+
+// boolToFloat returns 1 for true and 0 for false.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// jobStatus maps the status of a Harbor job to one of jobStatuses. Depending
+// on the release and the kind of job Harbor calls success finished or
+// succeed, and so on.
+func jobStatus(status string) string {
+	switch status = strings.ToLower(status); status {
+	case "finished", "succeed":
+		return "success"
+	case "failed":
+		return "error"
+	case "inprogress", "in_progress":
+		return "running"
+	}
+	return status
+}
+
+// jobFinished reports whether a job in the given status is done.
+func jobFinished(status string) bool {
+	return status == "success" || status == "error" || status == "stopped"
+}
+
+// unixSeconds returns t as seconds since the epoch.
+func unixSeconds(t harbor.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// ping times a ping Harbor does on behalf of the exporter, of a registry or
+// a P2P provider named what. A failed ping is not an error, only running out
+// of time or lacking the permission to ping is. keyvals describe the
+// endpoint when logging the failure.
+func ping(ctx context.Context, logger log.Logger, what string, f func() error, keyvals ...interface{}) (bool, time.Duration, error) {
+	start := time.Now()
+	err := f()
+	took := time.Since(start)
+	if ctx.Err() != nil {
+		return false, took, ctx.Err()
+	}
+	if harbor.IsForbidden(err) {
+		return false, took, fmt.Errorf("error pinging %s: %s", what, err)
+	}
+	if err != nil {
+		level.Warn(logger).Log(append(append([]interface{}{"msg", "Endpoint not reachable"}, keyvals...), "err", err)...)
+		return false, took, nil
+	}
+	return true, took, nil
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// TestMain gives the flags their defaults, which kingpin only sets while
+// parsing the command line.
+func TestMain(m *testing.M) {
+	if err := parseFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(m.Run())
+}
+
+// parseFlags parses args as the command line. Flags not given get their
+// defaults back.
+func parseFlags(args ...string) error {
+	// Repeatable flags append to what they hold.
+	*schedulesExpected = nil
+	_, err := kingpin.CommandLine.Parse(args)
+	return err
+}
+
+// setFlags parses args as the command line for the rest of a test. Defer the
+// returned function to put the defaults back.
+func setFlags(t *testing.T, args ...string) func() {
+	t.Helper()
+	if err := parseFlags(args...); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if err := parseFlags(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var fqNameRE = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// sample is a metric flattened for comparison in tests.
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+func toSample(t *testing.T, m prometheus.Metric) sample {
+	t.Helper()
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatalf("writing metric: %s", err)
+	}
+	s := sample{labels: make(map[string]string)}
+	if match := fqNameRE.FindStringSubmatch(m.Desc().String()); match != nil {
+		s.name = match[1]
+	}
+	for _, l := range pb.GetLabel() {
+		s.labels[l.GetName()] = l.GetValue()
+	}
+	switch {
+	case pb.Gauge != nil:
+		s.value = pb.GetGauge().GetValue()
+	case pb.Counter != nil:
+		s.value = pb.GetCounter().GetValue()
+	case pb.Untyped != nil:
+		s.value = pb.GetUntyped().GetValue()
+	}
+	return s
+}
+
+// collect runs c once and returns what it exported.
+func collect(t *testing.T, c Collector) ([]sample, error) {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Update(context.Background(), ch)
+		close(ch)
+	}()
+	var samples []sample
+	for m := range ch {
+		samples = append(samples, toSample(t, m))
+	}
+	return samples, <-done
+}
+
+// find returns the value of the sample with the given name and labels, which
+// may be a subset of its labels.
+func find(samples []sample, name string, labels ...string) (float64, bool) {
+next:
+	for _, s := range samples {
+		if s.name != name {
+			continue
+		}
+		for i := 0; i+1 < len(labels); i += 2 {
+			if s.labels[labels[i]] != labels[i+1] {
+				continue next
+			}
+		}
+		return s.value, true
+	}
+	return 0, false
+}
+
+// count returns the number of samples with the given name.
+func count(samples []sample, name string) int {
+	n := 0
+	for _, s := range samples {
+		if s.name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// testHarbor returns a client of a Harbor 2.7 served by handler, which sees
+// the paths below either API root, e.g. "/projects".
+func testHarbor(handler http.HandlerFunc) (HarborClient, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, root := range []string{harbor.APIPathV2, harbor.APIPathV1} {
+			if strings.HasPrefix(r.URL.Path, root+"/") {
+				r.URL.Path = strings.TrimPrefix(r.URL.Path, root)
+				break
+			}
+		}
+		handler(w, r)
+	}))
+	hc := harbor.NewClient(srv.URL, "", "", nil)
+	hc.APIPath = harbor.APIPathV2
+	hc.Version = harbor.Version{Major: 2, Minor: 7}
+	hc.VersionKnown = true
+	hc.Capabilities = harbor.NewCapabilities(hc.Version, nil)
+	return HarborClient{Client: hc, logger: log.NewNopLogger()}, srv
+}
+
+// collectorFunc turns a function into a Collector.
+type collectorFunc func(ctx context.Context, ch chan<- prometheus.Metric) error
+
+func (f collectorFunc) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return f(ctx, ch)
+}
+
+var testDesc = prometheus.NewDesc("test_metric", "Test metric.", []string{"step"}, nil)
+
+func testMetric(step string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 1, step)
+}
+
+// execTest runs c through execute and returns the success and all samples.
+func execTest(t *testing.T, c Collector, timeout time.Duration) (bool, []sample) {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	ok := execute(context.Background(), "test", timeout, c, ch, log.NewNopLogger())
+	close(ch)
+	var samples []sample
+	for m := range ch {
+		samples = append(samples, toSample(t, m))
+	}
+	return ok, samples
+}
+
+func checkScrape(t *testing.T, samples []sample, success float64, maxDuration time.Duration) {
+	t.Helper()
+	v, ok := find(samples, "harbor_exporter_collector_success", "collector", "test")
+	if !ok || v != success {
+		t.Errorf("collector_success = %v (found %v), want %v", v, ok, success)
+	}
+	d, ok := find(samples, "harbor_exporter_collector_duration_seconds", "collector", "test")
+	if !ok {
+		t.Fatal("collector_duration_seconds missing")
+	}
+	if d < 0 || d > maxDuration.Seconds() {
+		t.Errorf("collector_duration_seconds = %v, want at most %v", d, maxDuration.Seconds())
+	}
+}
+
+func TestExecuteSuccess(t *testing.T) {
+	c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		ch <- testMetric("one")
+		ch <- testMetric("two")
+		return nil
+	})
+	ok, samples := execTest(t, c, time.Second)
+	if !ok {
+		t.Error("execute reported failure")
+	}
+	if n := count(samples, "test_metric"); n != 2 {
+		t.Errorf("got %d test metrics, want 2", n)
+	}
+	checkScrape(t, samples, 1, time.Second)
+}
+
+func TestExecuteError(t *testing.T) {
+	c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		ch <- testMetric("before")
+		return errors.New("boom")
+	})
+	ok, samples := execTest(t, c, time.Second)
+	if ok {
+		t.Error("execute reported success")
+	}
+	if n := count(samples, "test_metric"); n != 1 {
+		t.Errorf("got %d test metrics, want 1", n)
+	}
+	checkScrape(t, samples, 0, time.Second)
+}
+
+// A slow collector honours its context and gives up at the deadline. What it
+// sent before is kept.
+func TestExecuteSlow(t *testing.T) {
+	c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		ch <- testMetric("before")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+			ch <- testMetric("after")
+			return nil
+		}
+	})
+	begin := time.Now()
+	ok, samples := execTest(t, c, 50*time.Millisecond)
+	if time.Since(begin) > 5*time.Second {
+		t.Fatal("execute did not stop at the deadline")
+	}
+	if ok {
+		t.Error("execute reported success")
+	}
+	if _, found := find(samples, "test_metric", "step", "before"); !found {
+		t.Error("metric sent before the deadline was dropped")
+	}
+	if _, found := find(samples, "test_metric", "step", "after"); found {
+		t.Error("metric sent after the deadline was kept")
+	}
+	checkScrape(t, samples, 0, 5*time.Second)
+}
+
+// A blocking collector ignores its context. It is abandoned at the deadline,
+// and what it sends afterwards is drained so it can still finish.
+func TestExecuteBlocking(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		defer close(finished)
+		<-release
+		for i := 0; i < 10; i++ {
+			ch <- testMetric("late")
+		}
+		return nil
+	})
+	begin := time.Now()
+	ok, samples := execTest(t, c, 50*time.Millisecond)
+	if time.Since(begin) > 5*time.Second {
+		t.Fatal("execute did not stop at the deadline")
+	}
+	if ok {
+		t.Error("execute reported success")
+	}
+	if n := count(samples, "test_metric"); n != 0 {
+		t.Errorf("got %d metrics from the abandoned collector", n)
+	}
+	checkScrape(t, samples, 0, 5*time.Second)
+
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("abandoned collector is blocked sending metrics")
+	}
+}
+
+func TestExecutePanic(t *testing.T) {
+	c := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		ch <- testMetric("before")
+		panic("broken collector")
+	})
+	ch := make(chan prometheus.Metric, 100)
+	_, err := runCollector(context.Background(), "test", time.Second, c, ch, log.NewNopLogger())
+	if err == nil || !strings.Contains(err.Error(), "broken collector") {
+		t.Errorf("runCollector error = %v, want the panic", err)
+	}
+
+	ok, samples := execTest(t, c, time.Second)
+	if ok {
+		t.Error("execute reported success")
+	}
+	if n := count(samples, "test_metric"); n != 1 {
+		t.Errorf("got %d test metrics, want 1", n)
+	}
+	checkScrape(t, samples, 0, time.Second)
+}
+
+func TestEnabledCollectors(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		args     []string
+		conf     Config
+		enabled  []string
+		disabled []string
+		timeout  map[string]time.Duration
+		interval map[string]time.Duration
+	}{
+		{
+			name:     "defaults",
+			enabled:  []string{"gc", "replications"},
+			disabled: []string{"vulnerabilities"},
+			timeout:  map[string]time.Duration{"gc": 10 * time.Second},
+			interval: map[string]time.Duration{"gc": 0},
+		},
+		{
+			name:     "flags",
+			args:     []string{"--no-collector.gc", "--collector.vulnerabilities"},
+			enabled:  []string{"replications", "vulnerabilities"},
+			disabled: []string{"gc"},
+		},
+		{
+			name: "config",
+			conf: Config{Collectors: map[string]CollectorConfig{
+				"gc":              {Enabled: &off},
+				"vulnerabilities": {Enabled: &on},
+			}},
+			enabled:  []string{"replications", "vulnerabilities"},
+			disabled: []string{"gc"},
+		},
+		{
+			name: "config over flags",
+			args: []string{"--no-collector.gc", "--collector.vulnerabilities"},
+			conf: Config{Collectors: map[string]CollectorConfig{
+				"gc":              {Enabled: &on},
+				"vulnerabilities": {Enabled: &off},
+			}},
+			enabled:  []string{"gc"},
+			disabled: []string{"vulnerabilities"},
+		},
+		{
+			name: "config without enabled keeps the flag",
+			args: []string{"--no-collector.gc"},
+			conf: Config{Collectors: map[string]CollectorConfig{
+				"gc": {Timeout: time.Second},
+			}},
+			disabled: []string{"gc"},
+		},
+		{
+			name: "timeout and interval",
+			args: []string{"--collector.timeout=20s", "--collector.gc.timeout=3s", "--collector.replications.interval=1m"},
+			conf: Config{
+				Collection: CollectionConfig{Timeout: 5 * time.Second, Interval: 30 * time.Second},
+				Collectors: map[string]CollectorConfig{"robots": {Timeout: 7 * time.Second}},
+			},
+			enabled: []string{"gc", "replications", "robots", "quotas"},
+			timeout: map[string]time.Duration{
+				"gc":           3 * time.Second,
+				"robots":       7 * time.Second,
+				"quotas":       5 * time.Second,
+				"replications": 5 * time.Second,
+			},
+			interval: map[string]time.Duration{
+				"gc":           30 * time.Second,
+				"replications": time.Minute,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer setFlags(t, test.args...)()
+			enabled := enabledCollectors(&test.conf)
+			for _, name := range test.enabled {
+				if _, ok := enabled[name]; !ok {
+					t.Errorf("%s is disabled", name)
+				}
+			}
+			for _, name := range test.disabled {
+				if _, ok := enabled[name]; ok {
+					t.Errorf("%s is enabled", name)
+				}
+			}
+			for name, want := range test.timeout {
+				if got := enabled[name].timeout; got != want {
+					t.Errorf("%s timeout = %s, want %s", name, got, want)
+				}
+			}
+			for name, want := range test.interval {
+				if got := enabled[name].interval; got != want {
+					t.Errorf("%s interval = %s, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Every collector has its own success and duration, and harbor_up is 0 as
+// soon as one of them failed.
+func TestCollectSelfMetrics(t *testing.T) {
+	ok := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		ch <- testMetric("ok")
+		return nil
+	})
+	failing := collectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+		return errors.New("boom")
+	})
+	e := &Exporter{
+		ctx:        context.Background(),
+		client:     HarborClient{Client: harbor.NewClient("http://harbor", "", "", nil)},
+		logger:     log.NewNopLogger(),
+		collectors: map[string]Collector{"ok": ok, "failing": failing},
+		settings: map[string]collectorSettings{
+			"ok":      {timeout: time.Second},
+			"failing": {timeout: time.Second},
+		},
+		cache: newSnapshotCache(),
+		up:    newUpDesc(""),
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	e.Collect(ch)
+	close(ch)
+	var samples []sample
+	for m := range ch {
+		samples = append(samples, toSample(t, m))
+	}
+
+	for name, want := range map[string]float64{"ok": 1, "failing": 0} {
+		if v, found := find(samples, "harbor_exporter_collector_success", "collector", name); !found || v != want {
+			t.Errorf("collector_success{collector=%q} = %v (found %v), want %v", name, v, found, want)
+		}
+		if _, found := find(samples, "harbor_exporter_collector_duration_seconds", "collector", name); !found {
+			t.Errorf("collector_duration_seconds{collector=%q} missing", name)
+		}
+	}
+	if v, found := find(samples, "harbor_up"); !found || v != 0 {
+		t.Errorf("harbor_up = %v (found %v), want 0", v, found)
+	}
+	if v, found := find(samples, "harbor_exporter_version_known"); !found || v != 0 {
+		t.Errorf("version_known = %v (found %v), want 0", v, found)
+	}
+}
+
+func TestCollectorOptions(t *testing.T) {
+	defer setFlags(t,
+		"--collector.robots.expiry-window=24h",
+		"--collector.schedules.expected=SCAN_ALL",
+		"--collector.replications.history=5",
+		"--collector.vulnerabilities.source=api",
+	)()
+	conf := &Config{Collectors: map[string]CollectorConfig{
+		"robots":          {ExpiryWindow: 72 * time.Hour},
+		"schedules":       {Expected: []string{}},
+		"proxycache":      {Window: 6 * time.Hour},
+		"vulnerabilities": {Level: "project"},
+	}}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The config file wins, the flags fill in the rest.
+	if got := settingsFor("robots", conf).expiryWindow; got != 72*time.Hour {
+		t.Errorf("robots expiry window = %s, want 72h", got)
+	}
+	if got := settingsFor("schedules", conf).expected; len(got) != 0 {
+		t.Errorf("expected schedules = %v, want none", got)
+	}
+	if got := settingsFor("schedules", &Config{}).expected; len(got) != 1 || got[0] != "SCAN_ALL" {
+		t.Errorf("expected schedules from the flag = %v, want [SCAN_ALL]", got)
+	}
+	if got := settingsFor("proxycache", conf).window; got != 6*time.Hour {
+		t.Errorf("proxy cache window = %s, want 6h", got)
+	}
+	if got := settingsFor("replications", conf).history; got != 5 {
+		t.Errorf("replication history = %d, want 5", got)
+	}
+	vuln := settingsFor("vulnerabilities", conf)
+	if vuln.source != "api" || vuln.level != "project" {
+		t.Errorf("vulnerabilities source, level = %s, %s, want api, project", vuln.source, vuln.level)
+	}
+}
+
+func TestCollectorOptionsInvalid(t *testing.T) {
+	for name, cc := range map[string]map[string]CollectorConfig{
+		"option of another collector": {"gc": {Window: time.Hour}},
+		"negative":                    {"replications": {History: -1}},
+		"unknown source":              {"vulnerabilities": {Source: "cache"}},
+		"unknown level":               {"vulnerabilities": {Level: "artifact"}},
+	} {
+		conf := &Config{Collectors: cc}
+		if err := conf.validate(); err == nil {
+			t.Errorf("%s: %v accepted", name, cc)
+		}
+	}
+}
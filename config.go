@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the file given with --config.file. Everything set
+// in the file takes precedence over the matching command line flag.
+type Config struct {
+	Harbor     HarborConfig               `yaml:"harbor"`
+	Deployment DeploymentConfig           `yaml:"deployment"`
+	Database   DatabaseConfig             `yaml:"database"`
+	Collection CollectionConfig           `yaml:"collection"`
+	Collectors map[string]CollectorConfig `yaml:"collectors"`
+	Filters    FiltersConfig              `yaml:"filters"`
+	Modules    map[string]Module          `yaml:"modules"`
+
+	projectFilter projectFilter
+}
+
+// HarborConfig overrides the --harbor.* flags.
+type HarborConfig struct {
+	Server    string    `yaml:"server"`
+	Instance  string    `yaml:"instance"`
+	Username  string    `yaml:"username"`
+	Password  string    `yaml:"password"`
+	TLSConfig TLSConfig `yaml:"tls_config"`
+}
+
+// DeploymentConfig overrides the --deployment.mode, --compose.harbor-config
+// and --storage.path flags.
+type DeploymentConfig struct {
+	Mode              string `yaml:"mode"`
+	ComposeConfigFile string `yaml:"compose_harbor_config"`
+	StoragePath       string `yaml:"storage_path"`
+}
+
+// DatabaseConfig overrides the discovered Postgres settings. DSN replaces
+// all of them.
+type DatabaseConfig struct {
+	DSN      string `yaml:"dsn"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"dbname"`
+	SSLMode  string `yaml:"sslmode"`
+
+	MaxOpenConns     int           `yaml:"max_open_conns"`
+	MaxIdleConns     int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime"`
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+}
+
+// CollectionConfig holds the defaults of every collector.
+type CollectionConfig struct {
+	Timeout  time.Duration `yaml:"timeout"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// CollectorConfig holds the settings of a single collector. The options
+// below Interval only apply to the collector named in their comment and
+// override its --collector.<name>.* flag.
+type CollectorConfig struct {
+	Enabled  *bool         `yaml:"enabled"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Interval time.Duration `yaml:"interval"`
+
+	ExpiryWindow time.Duration `yaml:"expiry_window"` // robots
+	Expected     []string      `yaml:"expected"`      // schedules
+	Window       time.Duration `yaml:"window"`        // proxycache
+	History      int           `yaml:"history"`       // replications
+	Source       string        `yaml:"source"`        // vulnerabilities
+	Level        string        `yaml:"level"`         // vulnerabilities
+}
+
+// collectorOptions names the collector each option of CollectorConfig
+// belongs to.
+var collectorOptions = map[string]string{
+	"expiry_window": "robots",
+	"expected":      "schedules",
+	"window":        "proxycache",
+	"history":       "replications",
+	"source":        "vulnerabilities",
+	"level":         "vulnerabilities",
+}
+
+// options reports which collector specific options are set.
+func (c CollectorConfig) options() map[string]bool {
+	return map[string]bool{
+		"expiry_window": c.ExpiryWindow != 0,
+		"expected":      c.Expected != nil,
+		"window":        c.Window != 0,
+		"history":       c.History != 0,
+		"source":        c.Source != "",
+		"level":         c.Level != "",
+	}
+}
+
+// FiltersConfig limits which objects are exported by the collectors.
+type FiltersConfig struct {
+	Projects ProjectsFilter `yaml:"projects"`
+}
+
+// ProjectsFilter selects projects by name. A project is exported when it
+// matches Include, if set, and does not match Exclude, if set.
+type ProjectsFilter struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+}
+
+// Module holds everything needed to probe a Harbor given as target on
+// /probe. Targets is a regular expression the host of the target, with the
+// port if one is given, has to match; it is required for modules with
+// credentials, which are sent to the target.
+type Module struct {
+	Username   string        `yaml:"username"`
+	Password   string        `yaml:"password"`
+	Targets    string        `yaml:"targets"`
+	Timeout    time.Duration `yaml:"timeout"`
+	TLSConfig  TLSConfig     `yaml:"tls_config"`
+	Collectors []string      `yaml:"collectors"`
+
+	targets *regexp.Regexp
+}
+
+// allows reports whether the module may be used to probe target.
+func (m Module) allows(target string) bool {
+	if m.targets == nil {
+		return m.Username == "" && m.Password == ""
+	}
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return m.targets.MatchString(u.Host)
+}
+
+// TLSConfig configures how the exporter connects to Harbor over HTTPS.
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CAFile             string `yaml:"ca_file"`
+}
+
+// projectFilter is the compiled form of ProjectsFilter.
+type projectFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// match reports whether the project should be exported.
+func (f projectFilter) match(project string) bool {
+	if f.include != nil && !f.include.MatchString(project) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(project) {
+		return false
+	}
+	return true
+}
+
+// loadConfig reads and validates the config file. An empty path returns an
+// empty config so that only the flags apply.
+func loadConfig(path string) (*Config, error) {
+	conf := &Config{}
+	if path == "" {
+		return conf, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(content, conf); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+	if err := conf.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %s", path, err)
+	}
+	return conf, nil
+}
+
+func (c *Config) validate() error {
+	if c.Collection.Timeout < 0 || c.Collection.Interval < 0 {
+		return fmt.Errorf("collection: timeout and interval must not be negative")
+	}
+	for name, collector := range c.Collectors {
+		if _, ok := factories[name]; !ok {
+			return fmt.Errorf("collectors: unknown collector %q", name)
+		}
+		if collector.Timeout < 0 || collector.Interval < 0 {
+			return fmt.Errorf("collectors: %s: timeout and interval must not be negative", name)
+		}
+		for option, set := range collector.options() {
+			if set && collectorOptions[option] != name {
+				return fmt.Errorf("collectors: %s: %s only applies to the %s collector", name, option, collectorOptions[option])
+			}
+		}
+		if collector.ExpiryWindow < 0 || collector.Window < 0 || collector.History < 0 {
+			return fmt.Errorf("collectors: %s: options must not be negative", name)
+		}
+		if s := collector.Source; s != "" && s != "auto" && s != "database" && s != "api" {
+			return fmt.Errorf("collectors: %s: unknown source %q", name, s)
+		}
+		if l := collector.Level; l != "" && l != "repository" && l != "project" {
+			return fmt.Errorf("collectors: %s: unknown level %q", name, l)
+		}
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 || c.Database.StatementTimeout < 0 {
+		return fmt.Errorf("database: pool settings must not be negative")
+	}
+	if m := c.Deployment.Mode; m != "" && m != modeKubernetes && m != modeCompose && m != modeStandalone {
+		return fmt.Errorf("deployment: unknown mode %q", m)
+	}
+
+	var err error
+	if c.Filters.Projects.Include != "" {
+		if c.projectFilter.include, err = regexp.Compile("^(?:" + c.Filters.Projects.Include + ")$"); err != nil {
+			return fmt.Errorf("filters: projects: include: %s", err)
+		}
+	}
+	if c.Filters.Projects.Exclude != "" {
+		if c.projectFilter.exclude, err = regexp.Compile("^(?:" + c.Filters.Projects.Exclude + ")$"); err != nil {
+			return fmt.Errorf("filters: projects: exclude: %s", err)
+		}
+	}
+
+	for name, module := range c.Modules {
+		if len(module.Collectors) == 0 {
+			module.Collectors = []string{"statistics", "replications"}
+			c.Modules[name] = module
+		}
+		for _, collector := range module.Collectors {
+			if _, ok := factories[collector]; !ok {
+				return fmt.Errorf("module %s: unknown collector %q", name, collector)
+			}
+		}
+		if module.Timeout < 0 {
+			return fmt.Errorf("module %s: timeout must not be negative", name)
+		}
+		if module.Targets == "" {
+			if module.Username != "" || module.Password != "" {
+				return fmt.Errorf("module %s: targets is required for modules with credentials", name)
+			}
+			continue
+		}
+		if module.targets, err = regexp.Compile("^(?:" + module.Targets + ")$"); err != nil {
+			return fmt.Errorf("module %s: targets: %s", name, err)
+		}
+		c.Modules[name] = module
+	}
+	return nil
+}
+
+// apply returns opts with the values set in the harbor section replacing
+// those of the flags.
+func (h HarborConfig) apply(opts harborOpts) harborOpts {
+	if h.Server != "" {
+		opts.uri = h.Server
+	}
+	if h.Instance != "" {
+		opts.instance = h.Instance
+	}
+	if h.Username != "" {
+		opts.username = h.Username
+	}
+	if h.Password != "" {
+		opts.password = h.Password
+	}
+	if h.TLSConfig.InsecureSkipVerify {
+		opts.insecure = true
+	}
+	if h.TLSConfig.CAFile != "" {
+		opts.caFile = h.TLSConfig.CAFile
+	}
+	return opts
+}
+
+// apply returns opts with the values set in the deployment section replacing
+// those of the flags.
+func (d DeploymentConfig) apply(opts harborOpts) harborOpts {
+	if d.Mode != "" {
+		opts.mode = d.Mode
+	}
+	if d.ComposeConfigFile != "" {
+		opts.composeFile = d.ComposeConfigFile
+	}
+	if d.StoragePath != "" {
+		opts.storage = d.StoragePath
+	}
+	return opts
+}
+
+// apply returns p with the values set in the database section replacing the
+// discovered ones.
+func (d DatabaseConfig) apply(p postgresParams) postgresParams {
+	if d.DSN != "" {
+		return postgresParams{dsn: d.DSN}
+	}
+	if d.Host != "" {
+		p.host = d.Host
+	}
+	if d.Port != "" {
+		p.port = d.Port
+	}
+	if d.User != "" {
+		p.user = d.User
+	}
+	if d.Password != "" {
+		p.password = d.Password
+	}
+	if d.DBName != "" {
+		p.dbname = d.DBName
+	}
+	if d.SSLMode != "" {
+		p.sslmode = d.SSLMode
+	}
+	return p
+}
+
+// poolOpts returns the pool settings of the database section, falling back
+// to the --database.* flags.
+func (d DatabaseConfig) poolOpts() poolOpts {
+	opts := poolOpts{
+		maxOpenConns:     *dbMaxOpenConns,
+		maxIdleConns:     *dbMaxIdleConns,
+		connMaxLifetime:  *dbConnMaxLifetime,
+		statementTimeout: *dbStatementTimeout,
+	}
+	if d.MaxOpenConns > 0 {
+		opts.maxOpenConns = d.MaxOpenConns
+	}
+	if d.MaxIdleConns > 0 {
+		opts.maxIdleConns = d.MaxIdleConns
+	}
+	if d.ConnMaxLifetime > 0 {
+		opts.connMaxLifetime = d.ConnMaxLifetime
+	}
+	if d.StatementTimeout > 0 {
+		opts.statementTimeout = d.StatementTimeout
+	}
+	return opts
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Sunday afternoon.
+	base := time.Date(2026, 10, 18, 13, 45, 30, 500000000, time.UTC)
+	tests := []struct {
+		spec string
+		from time.Time
+		want string
+	}{
+		// Six fields with seconds, as Harbor writes them.
+		{spec: "0 0 0 * * *", want: "2026-10-19T00:00:00Z"},
+		{spec: "*/10 * * * * *", want: "2026-10-18T13:45:40Z"},
+		{spec: "0 */15 * * * *", want: "2026-10-18T14:00:00Z"},
+		{spec: "0 5/20 * * * *", want: "2026-10-18T14:05:00Z"},
+		{spec: "0 0 8,20 * * *", want: "2026-10-18T20:00:00Z"},
+		// Five standard fields.
+		{spec: "5 4 * * *", want: "2026-10-19T04:05:00Z"},
+		{spec: "*/30 * * * *", want: "2026-10-18T14:00:00Z"},
+		// Descriptors.
+		{spec: "@hourly", want: "2026-10-18T14:00:00Z"},
+		{spec: "@daily", want: "2026-10-19T00:00:00Z"},
+		{spec: "@weekly", want: "2026-10-25T00:00:00Z"},
+		{spec: "@monthly", want: "2026-11-01T00:00:00Z"},
+		{spec: "@yearly", want: "2027-01-01T00:00:00Z"},
+		// Days of the week, by number, name, range and step.
+		{spec: "0 0 3 * * 6", want: "2026-10-24T03:00:00Z"},
+		{spec: "0 0 3 * * sat", want: "2026-10-24T03:00:00Z"},
+		{spec: "0 0 12 * * MON-FRI", want: "2026-10-19T12:00:00Z"},
+		{spec: "0 0 12 * * 2-6/2", want: "2026-10-20T12:00:00Z"},
+		{spec: "0 0 12 * * MON,THU", want: "2026-10-19T12:00:00Z"},
+		// Sunday is 0 or 7.
+		{spec: "0 0 0 * * 0", want: "2026-10-25T00:00:00Z"},
+		{spec: "0 0 0 * * 7", want: "2026-10-25T00:00:00Z"},
+		{spec: "0 0 0 * * SUN", want: "2026-10-25T00:00:00Z"},
+		{spec: "0 0 0 * * 5-7", want: "2026-10-23T00:00:00Z"},
+		// Months by number, name and range.
+		{spec: "0 30 9 1-7 JAN-MAR *", want: "2027-01-01T09:30:00Z"},
+		{spec: "0 0 0 15 2 *", want: "2027-02-15T00:00:00Z"},
+		{spec: "0 0 0 15 feb,aug *", want: "2027-02-15T00:00:00Z"},
+		{spec: "0 0 0 1 */6 *", want: "2027-01-01T00:00:00Z"},
+		// With only one of day of month and day of week restricted, that
+		// one applies; with both, either matches.
+		{spec: "0 0 0 1 * *", want: "2026-11-01T00:00:00Z"},
+		{spec: "0 0 0 1 * ?", want: "2026-11-01T00:00:00Z"},
+		{spec: "0 0 0 ? * ?", want: "2026-10-19T00:00:00Z"},
+		{spec: "0 0 0 1 * 7", want: "2026-10-25T00:00:00Z"},
+		{spec: "0 0 0 20 * 7", want: "2026-10-20T00:00:00Z"},
+		{spec: "0 0 0 13 * FRI", want: "2026-10-23T00:00:00Z"},
+		// Rare and impossible dates.
+		{spec: "0 0 0 29 2 *", want: "2028-02-29T00:00:00Z"},
+		{spec: "0 0 0 31 * *", want: "2026-10-31T00:00:00Z"},
+		{spec: "0 0 0 31 11 *", want: ""},
+		{spec: "0 0 0 30 2 *", want: ""},
+		{spec: "0 0 0 31 4,6,9,11 *", want: ""},
+		// The next run is strictly after the given time.
+		{spec: "@hourly", from: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), want: "2026-10-18T15:00:00Z"},
+		{spec: "0 0 0 31 12 *", from: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), want: "2027-12-31T00:00:00Z"},
+	}
+	for _, test := range tests {
+		s, err := parseCron(test.spec)
+		if err != nil {
+			t.Errorf("parseCron(%q): %s", test.spec, err)
+			continue
+		}
+		from := test.from
+		if from.IsZero() {
+			from = base
+		}
+		next := s.next(from)
+		got := ""
+		if !next.IsZero() {
+			got = next.Format(time.RFC3339)
+		}
+		if got != test.want {
+			t.Errorf("%q after %s: got %q, want %q", test.spec, from.Format(time.RFC3339), got, test.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * *",
+		"* * * *",
+		"0 0 0 * * * *",
+		"61 * * * * *",
+		"0 60 * * * *",
+		"0 0 24 * * *",
+		"0 0 0 0 * *",
+		"0 0 0 32 * *",
+		"0 0 0 * 0 *",
+		"0 0 0 * 13 *",
+		"0 0 0 * * 8",
+		"0 */0 * * * *",
+		"0 */x * * * *",
+		"0 5-2 * * * *",
+		"0 0 0 * FOO *",
+		"0 0 0 * * MON-",
+		"0 a * * * *",
+		"@fortnightly",
+	} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Deployment modes. They decide where the exporter looks for the Harbor
+// database and the registry storage.
+const (
+	modeKubernetes = "kubernetes"
+	modeCompose    = "compose"
+	modeStandalone = "standalone"
+)
+
+var deploymentModes = []string{modeKubernetes, modeCompose, modeStandalone}
+
+// environment is what the exporter knows about a Harbor installation besides
+// its API. Whatever could not be found is left empty and the collectors
+// needing it are skipped.
+type environment struct {
+	kubeClient KubeClient
+	pg         postgresParams
+	storage    string
+}
+
+// discoverEnvironment finds the database and storage of the Harbor
+// installation according to opts.mode. An explicit DSN or storage path
+// replaces what was discovered.
+func discoverEnvironment(opts harborOpts, logger log.Logger) (environment, error) {
+	var env environment
+	switch opts.mode {
+	case modeKubernetes:
+		env = discoverKubernetes(logger)
+	case modeCompose:
+		var err error
+		if env, err = discoverCompose(opts.composeFile, logger); err != nil {
+			return env, err
+		}
+	case modeStandalone:
+	default:
+		return env, fmt.Errorf("unknown deployment mode %q, must be one of %s", opts.mode, strings.Join(deploymentModes, ", "))
+	}
+	if opts.storage != "" {
+		env.storage = opts.storage
+	}
+	if opts.dsn != "" {
+		env.pg = postgresParams{dsn: opts.dsn}
+	}
+	return env, nil
+}
+
+// discoverKubernetes reads the database settings and storage location from
+// the config maps and secrets of the Harbor release the exporter runs next
+// to. Outside a cluster nothing is found.
+func discoverKubernetes(logger log.Logger) environment {
+	var env environment
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		level.Warn(logger).Log("msg", "Not running inside kubernetes, collectors depending on it are skipped", "err", err)
+		return env
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error creating kubernetes client", "err", err)
+		return env
+	}
+	// 得到exporter所在的命名空间
+	data, err := ioutil.ReadFile("/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		level.Error(logger).Log("msg", "Error to get namespace", "err", err)
+	}
+	env.kubeClient = KubeClient{
+		client:    clientset,
+		config:    config,
+		namespace: strings.TrimSpace(string(data)),
+	}
+
+	configmapList, err := clientset.CoreV1().ConfigMaps(env.kubeClient.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		level.Error(logger).Log("msg", "Error getting storage location", "err", err)
+	} else {
+		for _, configMap := range configmapList.Items {
+			if strings.Contains(configMap.Name, "harbor-registry") {
+				// 得到storage的存储位置
+				var registryConfig struct {
+					Storage struct {
+						Filesystem struct {
+							RootDirectory string `yaml:"rootdirectory"`
+						} `yaml:"filesystem"`
+					} `yaml:"storage"`
+				}
+				if err := yaml.Unmarshal([]byte(configMap.Data["config.yml"]), &registryConfig); err != nil {
+					level.Error(logger).Log("msg", "Error getting storage location", "err", err)
+					continue
+				}
+				env.storage = registryConfig.Storage.Filesystem.RootDirectory
+			} else if strings.Contains(configMap.Name, "harbor-core") {
+				// 得到pg相关信息
+				env.pg.user = configMap.Data["POSTGRESQL_USERNAME"]
+				env.pg.host = configMap.Data["POSTGRESQL_HOST"]
+				env.pg.port = configMap.Data["POSTGRESQL_PORT"]
+				env.pg.sslmode = configMap.Data["POSTGRESQL_SSLMODE"]
+				env.pg.dbname = configMap.Data["POSTGRESQL_DATABASE"]
+			}
+		}
+	}
+
+	secretList, err := clientset.CoreV1().Secrets(env.kubeClient.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		level.Error(logger).Log("msg", "Error getting postgres password", "err", err)
+	} else {
+		for _, secret := range secretList.Items {
+			if strings.Contains(secret.Name, "harbor-database") {
+				env.pg.password = string(secret.Data["POSTGRES_PASSWORD"])
+				break
+			}
+		}
+	}
+	return env
+}
+
+// composeConfig is the part of harbor.yml, the configuration file of the
+// Harbor installer, the exporter cares about.
+type composeConfig struct {
+	DataVolume string `yaml:"data_volume"`
+	Database   struct {
+		Password string `yaml:"password"`
+	} `yaml:"database"`
+	ExternalDatabase *struct {
+		Harbor struct {
+			Host     string `yaml:"host"`
+			Port     string `yaml:"port"`
+			DBName   string `yaml:"db_name"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			SSLMode  string `yaml:"ssl_mode"`
+		} `yaml:"harbor"`
+	} `yaml:"external_database"`
+	StorageService map[string]interface{} `yaml:"storage_service"`
+}
+
+// discoverCompose reads the database settings and the data volume from the
+// harbor.yml of a docker-compose installation. The bundled database is
+// reached by its container name, so the exporter has to be attached to the
+// harbor network.
+func discoverCompose(path string, logger log.Logger) (environment, error) {
+	var env environment
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return env, fmt.Errorf("reading harbor config: %s", err)
+	}
+	var conf composeConfig
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return env, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	if db := conf.ExternalDatabase; db != nil {
+		env.pg = postgresParams{
+			host:     db.Harbor.Host,
+			port:     db.Harbor.Port,
+			user:     db.Harbor.Username,
+			password: db.Harbor.Password,
+			dbname:   db.Harbor.DBName,
+			sslmode:  db.Harbor.SSLMode,
+		}
+	} else {
+		env.pg = postgresParams{
+			host:     "postgresql",
+			port:     "5432",
+			user:     "postgres",
+			password: conf.Database.Password,
+			dbname:   "registry",
+			sslmode:  "disable",
+		}
+	}
+
+	// Only the filesystem driver keeps the images below the data volume.
+	for driver := range conf.StorageService {
+		switch driver {
+		case "ca_bundle", "redirect", "filesystem":
+		default:
+			level.Info(logger).Log("msg", "Registry storage is not on the local filesystem", "driver", driver)
+			return env, nil
+		}
+	}
+	dataVolume := conf.DataVolume
+	if dataVolume == "" {
+		dataVolume = "/data"
+	}
+	env.storage = filepath.Join(dataVolume, "registry")
+	return env, nil
+}
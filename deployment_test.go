@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestDiscoverCompose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harbor_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundled := postgresParams{
+		host: "postgresql", port: "5432", user: "postgres", password: "root123",
+		dbname: "registry", sslmode: "disable",
+	}
+	tests := []struct {
+		name    string
+		config  string
+		pg      postgresParams
+		storage string
+		err     string
+	}{
+		{
+			name: "bundled database",
+			config: `
+data_volume: /srv/harbor
+database:
+  password: root123
+`,
+			pg:      bundled,
+			storage: "/srv/harbor/registry",
+		},
+		{
+			name: "external database",
+			config: `
+database:
+  password: unused
+external_database:
+  harbor:
+    host: db.example.com
+    port: "6432"
+    db_name: harbor
+    username: harbor
+    password: secret
+    ssl_mode: require
+`,
+			pg: postgresParams{
+				host: "db.example.com", port: "6432", user: "harbor", password: "secret",
+				dbname: "harbor", sslmode: "require",
+			},
+			storage: "/data/registry",
+		},
+		{
+			name: "default data volume",
+			config: `
+database:
+  password: root123
+`,
+			pg:      bundled,
+			storage: "/data/registry",
+		},
+		{
+			name: "filesystem storage",
+			config: `
+database:
+  password: root123
+storage_service:
+  ca_bundle: /etc/ca.crt
+  redirect:
+    disable: true
+  filesystem:
+    maxthreads: 100
+`,
+			pg:      bundled,
+			storage: "/data/registry",
+		},
+		{
+			name: "s3 storage",
+			config: `
+database:
+  password: root123
+storage_service:
+  s3:
+    bucket: harbor
+`,
+			pg: bundled,
+		},
+		{
+			name: "missing file",
+			err:  "reading harbor config",
+		},
+		{
+			name:   "invalid file",
+			config: "database: [",
+			err:    "parsing",
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("harbor%d.yml", i))
+			if test.config != "" {
+				if err := ioutil.WriteFile(path, []byte(test.config), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env, err := discoverCompose(path, log.NewNopLogger())
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.pg != test.pg {
+				t.Errorf("pg = %+v, want %+v", env.pg, test.pg)
+			}
+			if env.storage != test.storage {
+				t.Errorf("storage = %q, want %q", env.storage, test.storage)
+			}
+		})
+	}
+}
+
+// Without a DSN a standalone exporter knows no database, so the collectors
+// needing one are skipped.
+func TestStandaloneWithoutDatabase(t *testing.T) {
+	env, err := discoverEnvironment(harborOpts{mode: modeStandalone}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pg, err := (DatabaseConfig{}).apply(env.pg).postgres().open(poolOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.close()
+
+	hc, srv := testHarbor(vulnHarbor)
+	defer srv.Close()
+	settings := map[string]collectorSettings{
+		"database":        settingsFor("database", &Config{}),
+		"repositories":    settingsFor("repositories", &Config{}),
+		"systemvolumes":   settingsFor("systemvolumes", &Config{}),
+		"vulnerabilities": settingsFor("vulnerabilities", &Config{}),
+	}
+	vuln := settings["vulnerabilities"]
+	vuln.source = "database"
+	settings["vulnerabilities"] = vuln
+	e := &Exporter{
+		client:     hc,
+		logger:     log.NewNopLogger(),
+		pg:         pg,
+		collectors: make(map[string]Collector),
+	}
+	if err := e.initCollectors(settings); err != nil {
+		t.Fatal(err)
+	}
+	for name := range settings {
+		if _, ok := e.collectors[name]; ok {
+			t.Errorf("%s collector enabled without a database", name)
+		}
+	}
+}
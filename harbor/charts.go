@@ -0,0 +1,35 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetChartRepoHealth reports whether chartmuseum is healthy. It needs a
+// Harbor older than 2.8 installed with chartmuseum. Like the rest of the chart
+// repository API it stayed below /api on Harbor 2.x.
+func (c *Client) GetChartRepoHealth(ctx context.Context) (bool, error) {
+	if err := c.Require(CapChartmuseum); err != nil {
+		return false, err
+	}
+	var health struct {
+		Healthy bool `json:"healthy"`
+	}
+	if _, err := c.doAt(ctx, APIPathV1, http.MethodGet, "/chartrepo/health", nil, nil, &health); err != nil {
+		return false, err
+	}
+	return health.Healthy, nil
+}
+
+// ListCharts returns the charts in the chart repository of a project.
+func (c *Client) ListCharts(ctx context.Context, project string) ([]ChartInfo, error) {
+	if err := c.Require(CapChartmuseum); err != nil {
+		return nil, err
+	}
+	var charts []ChartInfo
+	if _, err := c.doAt(ctx, APIPathV1, http.MethodGet, "/chartrepo/"+url.PathEscape(project)+"/charts", nil, nil, &charts); err != nil {
+		return nil, err
+	}
+	return charts, nil
+}
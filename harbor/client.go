@@ -0,0 +1,310 @@
+// Package harbor is a client for the Harbor REST API. It covers the
+// resources the exporter reads, returns typed models and follows Harbor's
+// pagination, and can be used on its own by other tools.
+package harbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// APIPathV1 is the API root of Harbor 1.x.
+	APIPathV1 = "/api"
+	// APIPathV2 is the API root of Harbor 2.x.
+	APIPathV2 = "/api/v2.0"
+
+	// DefaultPageSize is the number of items requested per page.
+	DefaultPageSize = 100
+)
+
+// Client talks to a single Harbor instance.
+type Client struct {
+	// BaseURL is the scheme and host of Harbor, e.g. https://harbor.example.com.
+	BaseURL string
+	// APIPath is the API root, APIPathV1 or APIPathV2. DetectAPIPath fills it in.
+	APIPath  string
+	Username string
+	Password string
+	// PageSize is the page size used by the List methods.
+	PageSize   int
+	HTTPClient *http.Client
+
+	// Version, VersionKnown and Capabilities are filled in by
+	// DetectVersion. VersionKnown is false when Version was assumed.
+	Version      Version
+	VersionKnown bool
+	Capabilities Capabilities
+}
+
+// NewClient returns a client for the Harbor at baseURL. The API path still
+// has to be set, either directly or with DetectAPIPath.
+func NewClient(baseURL, username, password string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		Username:     username,
+		Password:     password,
+		PageSize:     DefaultPageSize,
+		HTTPClient:   httpClient,
+		Capabilities: Capabilities{},
+	}
+}
+
+// Error is returned when Harbor answers with a non-2xx status.
+type Error struct {
+	StatusCode int
+	Method     string
+	URL        string
+	// Errors is the error payload Harbor sends along, if any.
+	Errors []ErrorItem `json:"errors"`
+}
+
+// ErrorItem is a single entry of Harbor's error payload.
+type ErrorItem struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	for _, item := range e.Errors {
+		msg += fmt.Sprintf(": %s %s", item.Code, item.Message)
+	}
+	return msg
+}
+
+// IsNotFound reports whether err is a 404 answer from Harbor.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsForbidden reports whether err is a 401 or 403 answer from Harbor.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+func hasStatus(err error, status int) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == status
+}
+
+// DetectAPIPath sets APIPath to the newest API root answering on
+// /systeminfo.
+func (c *Client) DetectAPIPath(ctx context.Context) error {
+	for _, path := range []string{APIPathV2, APIPathV1} {
+		req, err := http.NewRequest(http.MethodGet, c.BaseURL+path+"/systeminfo", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			c.APIPath = path
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to determine harbor API version of %s", c.BaseURL)
+}
+
+// do sends a request to path below the API root and decodes the JSON answer
+// into out, unless out is nil. An out of type *[]byte receives the body as is.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (http.Header, error) {
+	return c.doAt(ctx, c.APIPath, method, path, query, in, out)
+}
+
+// doAt is do below the API root apiPath, for the parts of the API that did
+// not move to a newer root.
+func (c *Client) doAt(ctx context.Context, apiPath, method, path string, query url.Values, in, out interface{}) (http.Header, error) {
+	u := c.BaseURL + apiPath + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if _, ok := out.(*[]byte); ok {
+		req.Header.Set("Accept", "text/plain, */*")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode, Method: method, URL: u}
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		json.Unmarshal(b, apiErr)
+		return resp.Header, apiErr
+	}
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return resp.Header, nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = ioutil.ReadAll(resp.Body)
+		return resp.Header, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.Header, fmt.Errorf("decoding %s: %s", u, err)
+	}
+	return resp.Header, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	_, err := c.do(ctx, http.MethodGet, path, query, nil, out)
+	return err
+}
+
+// ListOptions narrows down a List call.
+type ListOptions struct {
+	// Query is passed as Harbor's q parameter, e.g. "name=~library".
+	Query string
+	// Sort is passed as Harbor's sort parameter, e.g. "-creation_time".
+	Sort string
+	// Limit stops the listing once at least that many items were fetched,
+	// 0 lists everything.
+	Limit int
+}
+
+func (o *ListOptions) values() url.Values {
+	v := url.Values{}
+	if o == nil {
+		return v
+	}
+	if o.Query != "" {
+		v.Set("q", o.Query)
+	}
+	if o.Sort != "" {
+		v.Set("sort", o.Sort)
+	}
+	return v
+}
+
+func (o *ListOptions) limit() int {
+	if o == nil {
+		return 0
+	}
+	return o.Limit
+}
+
+var linkNextRE = regexp.MustCompile(`<[^>]*>\s*;\s*rel="?next"?`)
+
+// list walks all pages of path. decode is called with the body of every page
+// and returns how many items it found on it.
+func (c *Client) list(ctx context.Context, path string, query url.Values, opts *ListOptions, decode func(body []byte) (int, error)) error {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	limit := opts.limit()
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	for k, v := range opts.values() {
+		query[k] = v
+	}
+
+	seen := 0
+	var prev json.RawMessage
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("page_size", strconv.Itoa(pageSize))
+
+		var raw json.RawMessage
+		header, err := c.do(ctx, http.MethodGet, path, query, nil, &raw)
+		if err != nil {
+			return err
+		}
+		// An endpoint that ignores page answers every page alike; the items
+		// were already decoded from the previous one.
+		if prev != nil && bytes.Equal(raw, prev) {
+			return nil
+		}
+		prev = raw
+		n, err := decode(raw)
+		if err != nil {
+			return fmt.Errorf("decoding %s: %s", path, err)
+		}
+		seen += n
+		// More items than asked for means page_size was ignored and
+		// everything came at once.
+		if n == 0 || n > pageSize || (limit > 0 && seen >= limit) {
+			return nil
+		}
+
+		// Harbor announces further pages with a Link header and the total
+		// with X-Total-Count; without either a short page is the last one.
+		more := n >= pageSize
+		if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+			more = seen < total
+		}
+		if link := header.Get("Link"); link != "" {
+			more = linkNextRE.MatchString(link)
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// count returns the number of items of path matching opts, as announced in
+// the X-Total-Count header of a single one item page.
+func (c *Client) count(ctx context.Context, path string, opts *ListOptions) (int64, error) {
+	query := opts.values()
+	query.Set("page", "1")
+	query.Set("page_size", "1")
+	header, err := c.do(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	total, err := strconv.ParseInt(header.Get("X-Total-Count"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no total count in answer of %s", path)
+	}
+	return total, nil
+}
+
+// escapeRepo escapes a repository name for use in a v2 API path, where the
+// slashes of nested names have to be encoded twice.
+func escapeRepo(name string) string {
+	return url.PathEscape(url.PathEscape(name))
+}
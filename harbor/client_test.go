@@ -0,0 +1,187 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// pagedServer serves total projects on /api/v2.0/projects. paginate turns
+// the requested page and page size into the projects returned and the
+// headers set, and every request is counted.
+type pagedServer struct {
+	total    int
+	requests int
+	paginate func(w http.ResponseWriter, page, size int) (from, to int)
+}
+
+func (s *pagedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests++
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	from, to := s.paginate(w, page, size)
+	if to > s.total {
+		to = s.total
+	}
+	if from > to {
+		from = to
+	}
+	projects := []Project{}
+	for i := from; i < to; i++ {
+		projects = append(projects, Project{ProjectID: int64(i + 1), Name: fmt.Sprintf("p%d", i+1)})
+	}
+	json.NewEncoder(w).Encode(projects)
+}
+
+// paged honours page and page size.
+func paged(w http.ResponseWriter, page, size int) (int, int) {
+	return (page - 1) * size, page * size
+}
+
+func listProjects(t *testing.T, s *pagedServer, pageSize int, opts *ListOptions) []Project {
+	t.Helper()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c := NewClient(srv.URL, "", "", nil)
+	c.APIPath = APIPathV2
+	c.PageSize = pageSize
+	projects, err := c.ListProjects(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return projects
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		pageSize int
+		opts     *ListOptions
+		paginate func(w http.ResponseWriter, page, size int) (int, int)
+		items    int
+		requests int
+	}{
+		{
+			name:     "short page",
+			total:    5,
+			pageSize: 2,
+			paginate: paged,
+			items:    5,
+			requests: 3,
+		},
+		{
+			name:     "full last page",
+			total:    4,
+			pageSize: 2,
+			paginate: paged,
+			items:    4,
+			requests: 3,
+		},
+		{
+			name:     "total count",
+			total:    4,
+			pageSize: 2,
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				w.Header().Set("X-Total-Count", "4")
+				return paged(w, page, size)
+			},
+			items:    4,
+			requests: 2,
+		},
+		{
+			name:     "link next",
+			total:    4,
+			pageSize: 2,
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				if page*size < 4 {
+					w.Header().Set("Link", fmt.Sprintf(`</api/v2.0/projects?page=%d&page_size=%d>; rel="next"`, page+1, size))
+				} else {
+					w.Header().Set("Link", fmt.Sprintf(`</api/v2.0/projects?page=%d&page_size=%d>; rel="prev"`, page-1, size))
+				}
+				return paged(w, page, size)
+			},
+			items:    4,
+			requests: 2,
+		},
+		{
+			name:     "link over total count",
+			total:    6,
+			pageSize: 2,
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				w.Header().Set("X-Total-Count", "2")
+				if page*size < 6 {
+					w.Header().Set("Link", `</api/v2.0/projects?page=2>; rel="next"`)
+				}
+				return paged(w, page, size)
+			},
+			items:    6,
+			requests: 3,
+		},
+		{
+			name:     "limit",
+			total:    10,
+			pageSize: 100,
+			opts:     &ListOptions{Limit: 3},
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				w.Header().Set("X-Total-Count", "10")
+				return paged(w, page, size)
+			},
+			items:    3,
+			requests: 1,
+		},
+		{
+			name:     "limit across pages",
+			total:    10,
+			pageSize: 2,
+			opts:     &ListOptions{Limit: 3},
+			paginate: paged,
+			items:    4,
+			requests: 2,
+		},
+		{
+			name:     "page size ignored",
+			total:    5,
+			pageSize: 2,
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				w.Header().Set("Link", `</api/v2.0/projects?page=2>; rel="next"`)
+				return 0, 5
+			},
+			items:    5,
+			requests: 1,
+		},
+		{
+			name:     "page ignored",
+			total:    5,
+			pageSize: 2,
+			paginate: func(w http.ResponseWriter, page, size int) (int, int) {
+				w.Header().Set("X-Total-Count", "5")
+				return 0, size
+			},
+			items:    2,
+			requests: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &pagedServer{total: test.total, paginate: test.paginate}
+			projects := listProjects(t, s, test.pageSize, test.opts)
+			if len(projects) != test.items {
+				t.Errorf("got %d projects, want %d", len(projects), test.items)
+			}
+			for i, p := range projects {
+				if p.ProjectID != int64(i+1) {
+					t.Errorf("project %d has ID %d", i, p.ProjectID)
+				}
+			}
+			if s.requests != test.requests {
+				t.Errorf("sent %d requests, want %d", s.requests, test.requests)
+			}
+		})
+	}
+}
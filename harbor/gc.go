@@ -0,0 +1,39 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// ListGCHistory returns past garbage collection runs.
+func (c *Client) ListGCHistory(ctx context.Context, opts *ListOptions) ([]GCHistory, error) {
+	var runs []GCHistory
+	err := c.list(ctx, "/system/gc", nil, opts, func(body []byte) (int, error) {
+		var page []GCHistory
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		runs = append(runs, page...)
+		return len(page), nil
+	})
+	return runs, err
+}
+
+// GetGCSchedule returns the schedule of garbage collection.
+func (c *Client) GetGCSchedule(ctx context.Context) (*GCHistory, error) {
+	var schedule GCHistory
+	if err := c.get(ctx, "/system/gc/schedule", nil, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetGCLog returns the job log of a garbage collection run.
+func (c *Client) GetGCLog(ctx context.Context, id int64) (string, error) {
+	var log []byte
+	if err := c.get(ctx, "/system/gc/"+strconv.FormatInt(id, 10)+"/log", nil, &log); err != nil {
+		return "", err
+	}
+	return string(log), nil
+}
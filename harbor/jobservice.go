@@ -0,0 +1,44 @@
+package harbor
+
+import (
+	"context"
+	"net/url"
+)
+
+// ListWorkerPools returns the jobservice worker pools. It needs Harbor 2.7 or
+// newer.
+func (c *Client) ListWorkerPools(ctx context.Context) ([]WorkerPool, error) {
+	if err := c.Require(CapJobservice); err != nil {
+		return nil, err
+	}
+	var pools []WorkerPool
+	if err := c.get(ctx, "/jobservice/pools", nil, &pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// ListWorkers returns the workers of a pool, or of all pools for poolID
+// "all".
+func (c *Client) ListWorkers(ctx context.Context, poolID string) ([]Worker, error) {
+	if err := c.Require(CapJobservice); err != nil {
+		return nil, err
+	}
+	var workers []Worker
+	if err := c.get(ctx, "/jobservice/pools/"+url.PathEscape(poolID)+"/workers", nil, &workers); err != nil {
+		return nil, err
+	}
+	return workers, nil
+}
+
+// ListJobQueues returns the jobservice queues.
+func (c *Client) ListJobQueues(ctx context.Context) ([]JobQueue, error) {
+	if err := c.Require(CapJobservice); err != nil {
+		return nil, err
+	}
+	var queues []JobQueue
+	if err := c.get(ctx, "/jobservice/queues", nil, &queues); err != nil {
+		return nil, err
+	}
+	return queues, nil
+}
@@ -0,0 +1,16 @@
+package harbor
+
+import (
+	"context"
+	"net/url"
+)
+
+// CountProjectLogs returns the number of audit log entries of a project
+// matching opts, e.g. with Query "operation=pull". It needs Harbor 2.0 or
+// newer.
+func (c *Client) CountProjectLogs(ctx context.Context, project string, opts *ListOptions) (int64, error) {
+	if err := c.Require(CapArtifacts); err != nil {
+		return 0, err
+	}
+	return c.count(ctx, "/projects/"+url.PathEscape(project)+"/logs", opts)
+}
@@ -0,0 +1,487 @@
+package harbor
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Time is a timestamp as sent by Harbor. Unlike time.Time it accepts null
+// and empty strings, which older releases send for unset times.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time)
+}
+
+// Statistics is the answer of /statistics.
+type Statistics struct {
+	PrivateProjectCount     int64 `json:"private_project_count"`
+	PrivateRepoCount        int64 `json:"private_repo_count"`
+	PublicProjectCount      int64 `json:"public_project_count"`
+	PublicRepoCount         int64 `json:"public_repo_count"`
+	TotalProjectCount       int64 `json:"total_project_count"`
+	TotalRepoCount          int64 `json:"total_repo_count"`
+	TotalStorageConsumption int64 `json:"total_storage_consumption"`
+}
+
+// SystemInfo is the answer of /systeminfo.
+type SystemInfo struct {
+	HarborVersion               string `json:"harbor_version"`
+	AuthMode                    string `json:"auth_mode"`
+	RegistryURL                 string `json:"registry_url"`
+	ExternalURL                 string `json:"external_url"`
+	ProjectCreationRestriction  string `json:"project_creation_restriction"`
+	SelfRegistration            bool   `json:"self_registration"`
+	HasCARoot                   bool   `json:"has_ca_root"`
+	ReadOnly                    bool   `json:"read_only"`
+	WithNotary                  bool   `json:"with_notary"`
+	WithChartmuseum             bool   `json:"with_chartmuseum"`
+	NotificationEnable          bool   `json:"notification_enable"`
+	RegistryStorageProviderName string `json:"registry_storage_provider_name"`
+}
+
+// Project is a Harbor project.
+type Project struct {
+	ProjectID    int64             `json:"project_id"`
+	Name         string            `json:"name"`
+	OwnerName    string            `json:"owner_name"`
+	RegistryID   int64             `json:"registry_id"`
+	RepoCount    int64             `json:"repo_count"`
+	ChartCount   int64             `json:"chart_count"`
+	Metadata     map[string]string `json:"metadata"`
+	CreationTime Time              `json:"creation_time"`
+	UpdateTime   Time              `json:"update_time"`
+}
+
+// Repository is a repository inside a project.
+type Repository struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	ProjectID     int64  `json:"project_id"`
+	ArtifactCount int64  `json:"artifact_count"`
+	// TagsCount is only sent by Harbor 1.x.
+	TagsCount    int64 `json:"tags_count"`
+	PullCount    int64 `json:"pull_count"`
+	CreationTime Time  `json:"creation_time"`
+	UpdateTime   Time  `json:"update_time"`
+}
+
+// Artifact is an image, chart or other OCI artifact of a repository.
+type Artifact struct {
+	ID           int64                           `json:"id"`
+	Type         string                          `json:"type"`
+	MediaType    string                          `json:"media_type"`
+	Digest       string                          `json:"digest"`
+	Size         int64                           `json:"size"`
+	ProjectID    int64                           `json:"project_id"`
+	RepositoryID int64                           `json:"repository_id"`
+	PushTime     Time                            `json:"push_time"`
+	PullTime     Time                            `json:"pull_time"`
+	Tags         []Tag                           `json:"tags"`
+	ScanOverview map[string]VulnerabilitySummary `json:"scan_overview"`
+}
+
+// Tag is a tag pointing at an artifact.
+type Tag struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	PushTime  Time   `json:"push_time"`
+	PullTime  Time   `json:"pull_time"`
+	Immutable bool   `json:"immutable"`
+}
+
+// VulnerabilitySummary is the scan overview of an artifact for one report
+// mime type.
+type VulnerabilitySummary struct {
+	ReportID        string `json:"report_id"`
+	ScanStatus      string `json:"scan_status"`
+	Severity        string `json:"severity"`
+	Duration        int64  `json:"duration"`
+	StartTime       Time   `json:"start_time"`
+	EndTime         Time   `json:"end_time"`
+	CompletePercent int    `json:"complete_percent"`
+	Summary         *struct {
+		Total   int64            `json:"total"`
+		Fixable int64            `json:"fixable"`
+		Summary map[string]int64 `json:"summary"`
+	} `json:"summary"`
+}
+
+// Registry is a remote registry endpoint used for replication and proxy
+// caching.
+type Registry struct {
+	ID          int64               `json:"id"`
+	Name        string              `json:"name"`
+	Type        string              `json:"type"`
+	URL         string              `json:"url"`
+	Description string              `json:"description"`
+	Insecure    bool                `json:"insecure"`
+	Status      string              `json:"status"`
+	Credential  *RegistryCredential `json:"credential"`
+}
+
+// RegistryCredential describes how Harbor authenticates to a registry.
+type RegistryCredential struct {
+	Type      string `json:"type"`
+	AccessKey string `json:"access_key"`
+}
+
+// ReplicationPolicy is a replication rule.
+type ReplicationPolicy struct {
+	ID            int64               `json:"id"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description"`
+	Enabled       bool                `json:"enabled"`
+	SrcRegistry   *Registry           `json:"src_registry"`
+	DestRegistry  *Registry           `json:"dest_registry"`
+	DestNamespace string              `json:"dest_namespace"`
+	Trigger       *ReplicationTrigger `json:"trigger"`
+	Deletion      bool                `json:"deletion"`
+	Override      bool                `json:"override"`
+	CreationTime  Time                `json:"creation_time"`
+	UpdateTime    Time                `json:"update_time"`
+}
+
+// ReplicationTrigger tells when a replication policy runs.
+type ReplicationTrigger struct {
+	Type string `json:"type"`
+}
+
+// ReplicationExecution is a single run of a replication policy.
+type ReplicationExecution struct {
+	ID         int64  `json:"id"`
+	PolicyID   int64  `json:"policy_id"`
+	Status     string `json:"status"`
+	StatusText string `json:"status_text"`
+	Trigger    string `json:"trigger"`
+	Total      int64  `json:"total"`
+	Failed     int64  `json:"failed"`
+	Succeed    int64  `json:"succeed"`
+	InProgress int64  `json:"in_progress"`
+	Stopped    int64  `json:"stopped"`
+	StartTime  Time   `json:"start_time"`
+	EndTime    Time   `json:"end_time"`
+}
+
+// ReplicationTask replicates a single resource within an execution.
+type ReplicationTask struct {
+	ID           int64  `json:"id"`
+	ExecutionID  int64  `json:"execution_id"`
+	Status       string `json:"status"`
+	JobID        string `json:"job_id"`
+	Operation    string `json:"operation"`
+	ResourceType string `json:"resource_type"`
+	SrcResource  string `json:"src_resource"`
+	DstResource  string `json:"dst_resource"`
+	StartTime    Time   `json:"start_time"`
+	EndTime      Time   `json:"end_time"`
+}
+
+// Quota is the resource quota of a project.
+type Quota struct {
+	ID  int64 `json:"id"`
+	Ref struct {
+		ID        int64  `json:"id"`
+		Name      string `json:"name"`
+		OwnerName string `json:"owner_name"`
+	} `json:"ref"`
+	// Hard and Used are keyed by resource, "storage" on every release and
+	// "count" up to Harbor 2.0. A hard limit of -1 means unlimited.
+	Hard         map[string]int64 `json:"hard"`
+	Used         map[string]int64 `json:"used"`
+	CreationTime Time             `json:"creation_time"`
+	UpdateTime   Time             `json:"update_time"`
+}
+
+// Scanner is a registered scanner adapter.
+type Scanner struct {
+	UUID            string `json:"uuid"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	URL             string `json:"url"`
+	Disabled        bool   `json:"disabled"`
+	IsDefault       bool   `json:"is_default"`
+	SkipCertVerify  bool   `json:"skip_certVerify"`
+	UseInternalAddr bool   `json:"use_internal_addr"`
+	Adapter         string `json:"adapter"`
+	Vendor          string `json:"vendor"`
+	Version         string `json:"version"`
+	Health          string `json:"health"`
+	CreateTime      Time   `json:"create_time"`
+	UpdateTime      Time   `json:"update_time"`
+}
+
+// ScannerMetadata is what a scanner adapter reports about itself.
+type ScannerMetadata struct {
+	Scanner struct {
+		Name    string `json:"name"`
+		Vendor  string `json:"vendor"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Capabilities []struct {
+		ConsumesMimeTypes []string `json:"consumes_mime_types"`
+		ProducesMimeTypes []string `json:"produces_mime_types"`
+	} `json:"capabilities"`
+	Properties map[string]string `json:"properties"`
+}
+
+// Schedule tells when a periodic job runs.
+type Schedule struct {
+	// Type is one of None, Hourly, Daily, Weekly, Custom, Manual and
+	// Schedule.
+	Type string `json:"type"`
+	// Cron is a six field cron expression with seconds.
+	Cron string `json:"cron"`
+	// NextScheduledTime is only sent by Harbor 2.x.
+	NextScheduledTime Time `json:"next_scheduled_time"`
+}
+
+// GCHistory is a garbage collection run, or with GetGCSchedule the schedule
+// of garbage collection.
+type GCHistory struct {
+	ID      int64  `json:"id"`
+	JobName string `json:"job_name"`
+	JobKind string `json:"job_kind"`
+	// JobParameters is a JSON object, e.g. {"dry_run":true}.
+	JobParameters string    `json:"job_parameters"`
+	Schedule      *Schedule `json:"schedule"`
+	JobStatus     string    `json:"job_status"`
+	Deleted       bool      `json:"deleted"`
+	CreationTime  Time      `json:"creation_time"`
+	UpdateTime    Time      `json:"update_time"`
+}
+
+// DryRun reports whether the run only estimated what could be freed.
+func (h GCHistory) DryRun() bool {
+	var params struct {
+		DryRun bool `json:"dry_run"`
+	}
+	json.Unmarshal([]byte(h.JobParameters), &params)
+	return params.DryRun
+}
+
+// RetentionExecution is a run of a tag retention policy.
+type RetentionExecution struct {
+	ID        int64  `json:"id"`
+	PolicyID  int64  `json:"policy_id"`
+	Status    string `json:"status"`
+	Trigger   string `json:"trigger"`
+	DryRun    bool   `json:"dry_run"`
+	StartTime Time   `json:"start_time"`
+	EndTime   Time   `json:"end_time"`
+}
+
+// RetentionTask applies a retention policy to one repository.
+type RetentionTask struct {
+	ID          int64  `json:"id"`
+	ExecutionID int64  `json:"execution_id"`
+	Repository  string `json:"repository"`
+	JobID       string `json:"job_id"`
+	Status      string `json:"status"`
+	StatusCode  int    `json:"status_code"`
+	StartTime   Time   `json:"start_time"`
+	EndTime     Time   `json:"end_time"`
+	// Total is the number of artifacts looked at, Retained how many of
+	// them were kept.
+	Total    int64 `json:"total"`
+	Retained int64 `json:"retained"`
+}
+
+// Health is the answer of /health.
+type Health struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// ComponentHealth is the health of one Harbor component.
+type ComponentHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Error explains why an unhealthy component is unhealthy.
+	Error string `json:"error"`
+}
+
+// Healthy reports whether the component is healthy.
+func (h ComponentHealth) Healthy() bool {
+	return h.Status == "healthy"
+}
+
+// StorageInfo is the size of a storage volume of the registry in bytes.
+type StorageInfo struct {
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+}
+
+// Robot is a robot account. Harbor 2.2 and newer send Level, Disable and
+// Permissions, older releases Disabled, Access and ProjectID.
+type Robot struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Level       string `json:"level"`
+	ProjectID   int64  `json:"project_id"`
+	Disable     bool   `json:"disable"`
+	Disabled    bool   `json:"disabled"`
+	// ExpiresAt is in seconds since the epoch, -1 for never.
+	ExpiresAt    int64             `json:"expires_at"`
+	Permissions  []RobotPermission `json:"permissions"`
+	Access       []RobotAccess     `json:"access"`
+	CreationTime Time              `json:"creation_time"`
+	UpdateTime   Time              `json:"update_time"`
+}
+
+// RobotPermission grants a robot access within a namespace, a project name
+// or "*" for all projects.
+type RobotPermission struct {
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace"`
+	Access    []RobotAccess `json:"access"`
+}
+
+// RobotAccess is a single action a robot may perform.
+type RobotAccess struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Effect   string `json:"effect"`
+}
+
+// IsDisabled reports whether the robot is disabled.
+func (r Robot) IsDisabled() bool {
+	return r.Disable || r.Disabled
+}
+
+// PermissionCount returns the number of actions granted to the robot.
+func (r Robot) PermissionCount() int {
+	n := len(r.Access)
+	for _, p := range r.Permissions {
+		n += len(p.Access)
+	}
+	return n
+}
+
+// WorkerPool is a jobservice worker pool.
+type WorkerPool struct {
+	PID          int64  `json:"pid"`
+	WorkerPoolID string `json:"worker_pool_id"`
+	Host         string `json:"host"`
+	Concurrency  int64  `json:"concurrency"`
+	StartAt      Time   `json:"start_at"`
+	HeartbeatAt  Time   `json:"heartbeat_at"`
+}
+
+// Worker is a jobservice worker. JobID is empty while it is idle.
+type Worker struct {
+	ID      string `json:"id"`
+	PoolID  string `json:"pool_id"`
+	JobName string `json:"job_name"`
+	JobID   string `json:"job_id"`
+	StartAt Time   `json:"start_at"`
+	CheckIn string `json:"check_in"`
+}
+
+// JobQueue is the jobservice queue of one job type.
+type JobQueue struct {
+	JobType string `json:"job_type"`
+	Count   int64  `json:"count"`
+	// Latency is the age in seconds of the oldest job waiting.
+	Latency int64 `json:"latency"`
+	Paused  bool  `json:"paused"`
+}
+
+// ScheduleTask is a schedule of a periodic job. VendorType is the kind of
+// job, e.g. GARBAGE_COLLECTION or SCAN_ALL, and VendorID the policy it
+// belongs to where there can be several.
+type ScheduleTask struct {
+	ID         int64  `json:"id"`
+	VendorType string `json:"vendor_type"`
+	VendorID   int64  `json:"vendor_id"`
+	Cron       string `json:"cron"`
+	UpdateTime Time   `json:"update_time"`
+}
+
+// PreheatInstance is a P2P provider instance, e.g. Dragonfly or Kraken.
+type PreheatInstance struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Vendor      string `json:"vendor"`
+	Endpoint    string `json:"endpoint"`
+	AuthMode    string `json:"auth_mode"`
+	Status      string `json:"status"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`
+	Insecure    bool   `json:"insecure"`
+}
+
+// PreheatPolicy is a preheat policy of a project.
+type PreheatPolicy struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	ProjectID    int64  `json:"project_id"`
+	ProviderID   int64  `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	// Filters and Trigger are JSON documents in a string.
+	Filters      string `json:"filters"`
+	Trigger      string `json:"trigger"`
+	Enabled      bool   `json:"enabled"`
+	CreationTime Time   `json:"creation_time"`
+	UpdateTime   Time   `json:"update_time"`
+}
+
+// Execution is a run of a job handled by Harbor's task manager, e.g. of a
+// preheat policy.
+type Execution struct {
+	ID            int64             `json:"id"`
+	VendorType    string            `json:"vendor_type"`
+	VendorID      int64             `json:"vendor_id"`
+	Status        string            `json:"status"`
+	StatusMessage string            `json:"status_message"`
+	Trigger       string            `json:"trigger"`
+	Metrics       *ExecutionMetrics `json:"metrics"`
+	StartTime     Time              `json:"start_time"`
+	EndTime       Time              `json:"end_time"`
+}
+
+// ExecutionMetrics counts the tasks of an execution by status.
+type ExecutionMetrics struct {
+	TaskCount          int64 `json:"task_count"`
+	SuccessTaskCount   int64 `json:"success_task_count"`
+	ErrorTaskCount     int64 `json:"error_task_count"`
+	PendingTaskCount   int64 `json:"pending_task_count"`
+	RunningTaskCount   int64 `json:"running_task_count"`
+	ScheduledTaskCount int64 `json:"scheduled_task_count"`
+	StoppedTaskCount   int64 `json:"stopped_task_count"`
+}
+
+// ChartInfo is a Helm chart of a chart repository with all its versions.
+type ChartInfo struct {
+	Name          string `json:"name"`
+	TotalVersions int64  `json:"total_versions"`
+	LatestVersion string `json:"latest_version"`
+	Created       Time   `json:"created"`
+	Updated       Time   `json:"updated"`
+	Deprecated    bool   `json:"deprecated"`
+}
@@ -0,0 +1,75 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// ListPreheatInstances returns the P2P provider instances. It needs Harbor
+// 2.1 or newer.
+func (c *Client) ListPreheatInstances(ctx context.Context, opts *ListOptions) ([]PreheatInstance, error) {
+	if err := c.Require(CapP2PPreheat); err != nil {
+		return nil, err
+	}
+	var instances []PreheatInstance
+	err := c.list(ctx, "/p2p/preheat/instances", nil, opts, func(body []byte) (int, error) {
+		var page []PreheatInstance
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		instances = append(instances, page...)
+		return len(page), nil
+	})
+	return instances, err
+}
+
+// PingPreheatInstance asks Harbor to check that it can reach the P2P
+// provider instance with the given ID.
+func (c *Client) PingPreheatInstance(ctx context.Context, id int64) error {
+	if err := c.Require(CapP2PPreheat); err != nil {
+		return err
+	}
+	in := struct {
+		ID int64 `json:"id"`
+	}{id}
+	_, err := c.do(ctx, http.MethodPost, "/p2p/preheat/instances/ping", nil, in, nil)
+	return err
+}
+
+// ListPreheatPolicies returns the preheat policies of a project.
+func (c *Client) ListPreheatPolicies(ctx context.Context, project string, opts *ListOptions) ([]PreheatPolicy, error) {
+	if err := c.Require(CapP2PPreheat); err != nil {
+		return nil, err
+	}
+	var policies []PreheatPolicy
+	err := c.list(ctx, "/projects/"+url.PathEscape(project)+"/preheat/policies", nil, opts, func(body []byte) (int, error) {
+		var page []PreheatPolicy
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		policies = append(policies, page...)
+		return len(page), nil
+	})
+	return policies, err
+}
+
+// ListPreheatExecutions returns the executions of a preheat policy, newest
+// first.
+func (c *Client) ListPreheatExecutions(ctx context.Context, project, policy string, opts *ListOptions) ([]Execution, error) {
+	if err := c.Require(CapP2PPreheat); err != nil {
+		return nil, err
+	}
+	path := "/projects/" + url.PathEscape(project) + "/preheat/policies/" + url.PathEscape(policy) + "/executions"
+	var executions []Execution
+	err := c.list(ctx, path, nil, opts, func(body []byte) (int, error) {
+		var page []Execution
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		executions = append(executions, page...)
+		return len(page), nil
+	})
+	return executions, err
+}
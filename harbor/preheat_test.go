@@ -0,0 +1,92 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListPreheatInstances(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/p2p/preheat/instances" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"id": 1, "name": "dragonfly", "vendor": "dragonfly", "endpoint": "http://dragonfly:8080", "enabled": true, "default": true},
+			{"id": 2, "name": "kraken", "vendor": "kraken", "endpoint": "http://kraken", "enabled": false}
+		]`))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "", "", nil)
+	c.APIPath = APIPathV2
+
+	instances, err := c.ListPreheatInstances(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PreheatInstance{
+		{ID: 1, Name: "dragonfly", Vendor: "dragonfly", Endpoint: "http://dragonfly:8080", Enabled: true, Default: true},
+		{ID: 2, Name: "kraken", Vendor: "kraken", Endpoint: "http://kraken"},
+	}
+	if len(instances) != len(want) {
+		t.Fatalf("got %d instances, want %d", len(instances), len(want))
+	}
+	for i := range want {
+		if instances[i] != want[i] {
+			t.Errorf("instance %d = %+v, want %+v", i, instances[i], want[i])
+		}
+	}
+}
+
+func TestPingPreheatInstance(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2.0/p2p/preheat/instances/ping" {
+			http.NotFound(w, r)
+			return
+		}
+		var in struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch in.ID {
+		case 1:
+			w.WriteHeader(http.StatusOK)
+		case 2:
+			http.Error(w, `{"errors": [{"code": "FORBIDDEN", "message": "forbidden"}]}`, http.StatusForbidden)
+		default:
+			http.Error(w, `{"errors": [{"code": "UNKNOWN", "message": "connection refused"}]}`, http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "", "", nil)
+	c.APIPath = APIPathV2
+
+	if err := c.PingPreheatInstance(context.Background(), 1); err != nil {
+		t.Errorf("healthy instance: %s", err)
+	}
+	if err := c.PingPreheatInstance(context.Background(), 2); !IsForbidden(err) {
+		t.Errorf("forbidden ping: got %v", err)
+	}
+	err := c.PingPreheatInstance(context.Background(), 3)
+	if err == nil || IsForbidden(err) {
+		t.Errorf("unreachable instance: got %v", err)
+	}
+}
+
+func TestPreheatUnsupported(t *testing.T) {
+	c := NewClient("http://harbor", "", "", nil)
+	c.Version = Version{Major: 2, Minor: 0}
+	c.Capabilities = NewCapabilities(c.Version, nil)
+	if _, err := c.ListPreheatInstances(context.Background(), nil); !IsUnsupported(err) {
+		t.Errorf("ListPreheatInstances on 2.0: got %v", err)
+	}
+	if err := c.PingPreheatInstance(context.Background(), 1); !IsUnsupported(err) {
+		t.Errorf("PingPreheatInstance on 2.0: got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ListProjects returns all projects visible to the user.
+func (c *Client) ListProjects(ctx context.Context, opts *ListOptions) ([]Project, error) {
+	var projects []Project
+	err := c.list(ctx, "/projects", nil, opts, func(body []byte) (int, error) {
+		var page []Project
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		projects = append(projects, page...)
+		return len(page), nil
+	})
+	return projects, err
+}
+
+// ListRepositories returns the repositories of a project.
+func (c *Client) ListRepositories(ctx context.Context, project Project, opts *ListOptions) ([]Repository, error) {
+	path := "/projects/" + url.PathEscape(project.Name) + "/repositories"
+	var query url.Values
+	if c.APIPath == APIPathV1 {
+		path = "/repositories"
+		query = url.Values{"project_id": {strconv.FormatInt(project.ProjectID, 10)}}
+	}
+	var repos []Repository
+	err := c.list(ctx, path, query, opts, func(body []byte) (int, error) {
+		var page []Repository
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		repos = append(repos, page...)
+		return len(page), nil
+	})
+	return repos, err
+}
+
+// ArtifactListOptions narrows down ListArtifacts.
+type ArtifactListOptions struct {
+	ListOptions
+	WithTag          bool
+	WithScanOverview bool
+}
+
+// ListArtifacts returns the artifacts of a repository. repository is the
+// full name including the project, as returned in Repository.Name. It needs
+// Harbor 2.0 or newer.
+func (c *Client) ListArtifacts(ctx context.Context, repository string, opts *ArtifactListOptions) ([]Artifact, error) {
+	if err := c.Require(CapArtifacts); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ArtifactListOptions{}
+	}
+	project, repo := splitRepository(repository)
+	query := url.Values{
+		"with_tag":           {strconv.FormatBool(opts.WithTag)},
+		"with_scan_overview": {strconv.FormatBool(opts.WithScanOverview)},
+	}
+	path := "/projects/" + url.PathEscape(project) + "/repositories/" + escapeRepo(repo) + "/artifacts"
+	var artifacts []Artifact
+	err := c.list(ctx, path, query, &opts.ListOptions, func(body []byte) (int, error) {
+		var page []Artifact
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		artifacts = append(artifacts, page...)
+		return len(page), nil
+	})
+	return artifacts, err
+}
+
+// splitRepository splits "project/some/repo" into "project" and "some/repo".
+func splitRepository(name string) (string, string) {
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
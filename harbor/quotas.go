@@ -0,0 +1,25 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// ListQuotas returns the quotas of all projects.
+func (c *Client) ListQuotas(ctx context.Context, opts *ListOptions) ([]Quota, error) {
+	if err := c.Require(CapQuotas); err != nil {
+		return nil, err
+	}
+	query := url.Values{"reference": {"project"}}
+	var quotas []Quota
+	err := c.list(ctx, "/quotas", query, opts, func(body []byte) (int, error) {
+		var page []Quota
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		quotas = append(quotas, page...)
+		return len(page), nil
+	})
+	return quotas, err
+}
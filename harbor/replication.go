@@ -0,0 +1,89 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListReplicationPolicies returns all replication policies.
+func (c *Client) ListReplicationPolicies(ctx context.Context, opts *ListOptions) ([]ReplicationPolicy, error) {
+	var policies []ReplicationPolicy
+	err := c.list(ctx, "/replication/policies", nil, opts, func(body []byte) (int, error) {
+		var page []ReplicationPolicy
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		policies = append(policies, page...)
+		return len(page), nil
+	})
+	return policies, err
+}
+
+// ListReplicationExecutions returns the executions of a replication policy,
+// newest first.
+func (c *Client) ListReplicationExecutions(ctx context.Context, policyID int64, opts *ListOptions) ([]ReplicationExecution, error) {
+	return c.ListReplicationExecutionsByStatus(ctx, policyID, "", opts)
+}
+
+// ListReplicationExecutionsByStatus returns the executions of a replication
+// policy with the given status, e.g. "Succeed", newest first. An empty status
+// matches all executions.
+func (c *Client) ListReplicationExecutionsByStatus(ctx context.Context, policyID int64, status string, opts *ListOptions) ([]ReplicationExecution, error) {
+	query := url.Values{"policy_id": {strconv.FormatInt(policyID, 10)}}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var executions []ReplicationExecution
+	err := c.list(ctx, "/replication/executions", query, opts, func(body []byte) (int, error) {
+		var page []ReplicationExecution
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		executions = append(executions, page...)
+		return len(page), nil
+	})
+	return executions, err
+}
+
+// ListReplicationTasks returns the tasks of a replication execution.
+func (c *Client) ListReplicationTasks(ctx context.Context, executionID int64, opts *ListOptions) ([]ReplicationTask, error) {
+	path := "/replication/executions/" + strconv.FormatInt(executionID, 10) + "/tasks"
+	var tasks []ReplicationTask
+	err := c.list(ctx, path, nil, opts, func(body []byte) (int, error) {
+		var page []ReplicationTask
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		tasks = append(tasks, page...)
+		return len(page), nil
+	})
+	return tasks, err
+}
+
+// ListRegistries returns the registry endpoints known to Harbor.
+func (c *Client) ListRegistries(ctx context.Context, opts *ListOptions) ([]Registry, error) {
+	var registries []Registry
+	err := c.list(ctx, "/registries", nil, opts, func(body []byte) (int, error) {
+		var page []Registry
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		registries = append(registries, page...)
+		return len(page), nil
+	})
+	return registries, err
+}
+
+// PingRegistry asks Harbor to check that it can reach and log in to the
+// registry endpoint with the given ID. Harbor answers with an error status,
+// mostly 400, when the endpoint is unreachable or rejects the credential.
+func (c *Client) PingRegistry(ctx context.Context, id int64) error {
+	in := struct {
+		ID int64 `json:"id"`
+	}{id}
+	_, err := c.do(ctx, http.MethodPost, "/registries/ping", nil, in, nil)
+	return err
+}
@@ -0,0 +1,44 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// ListRetentionExecutions returns the runs of a retention policy, newest
+// first.
+func (c *Client) ListRetentionExecutions(ctx context.Context, retentionID int64, opts *ListOptions) ([]RetentionExecution, error) {
+	if err := c.Require(CapRetention); err != nil {
+		return nil, err
+	}
+	var executions []RetentionExecution
+	path := "/retentions/" + strconv.FormatInt(retentionID, 10) + "/executions"
+	err := c.list(ctx, path, nil, opts, func(body []byte) (int, error) {
+		var page []RetentionExecution
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		executions = append(executions, page...)
+		return len(page), nil
+	})
+	return executions, err
+}
+
+// ListRetentionTasks returns the tasks of a retention execution.
+func (c *Client) ListRetentionTasks(ctx context.Context, retentionID, executionID int64, opts *ListOptions) ([]RetentionTask, error) {
+	if err := c.Require(CapRetention); err != nil {
+		return nil, err
+	}
+	var tasks []RetentionTask
+	path := "/retentions/" + strconv.FormatInt(retentionID, 10) + "/executions/" + strconv.FormatInt(executionID, 10) + "/tasks"
+	err := c.list(ctx, path, nil, opts, func(body []byte) (int, error) {
+		var page []RetentionTask
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		tasks = append(tasks, page...)
+		return len(page), nil
+	})
+	return tasks, err
+}
@@ -0,0 +1,35 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// ListRobots returns the system and project robot accounts. It needs Harbor
+// 2.2 or newer, use ListProjectRobots before.
+func (c *Client) ListRobots(ctx context.Context, opts *ListOptions) ([]Robot, error) {
+	if err := c.Require(CapSystemRobots); err != nil {
+		return nil, err
+	}
+	return c.listRobots(ctx, "/robots", opts)
+}
+
+// ListProjectRobots returns the robot accounts of a project with the API of
+// Harbor 2.1 and older.
+func (c *Client) ListProjectRobots(ctx context.Context, projectID int64, opts *ListOptions) ([]Robot, error) {
+	return c.listRobots(ctx, "/projects/"+strconv.FormatInt(projectID, 10)+"/robots", opts)
+}
+
+func (c *Client) listRobots(ctx context.Context, path string, opts *ListOptions) ([]Robot, error) {
+	var robots []Robot
+	err := c.list(ctx, path, nil, opts, func(body []byte) (int, error) {
+		var page []Robot
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		robots = append(robots, page...)
+		return len(page), nil
+	})
+	return robots, err
+}
@@ -0,0 +1,38 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// ListScanners returns the registered scanner adapters.
+func (c *Client) ListScanners(ctx context.Context, opts *ListOptions) ([]Scanner, error) {
+	if err := c.Require(CapScanners); err != nil {
+		return nil, err
+	}
+	var scanners []Scanner
+	err := c.list(ctx, "/scanners", nil, opts, func(body []byte) (int, error) {
+		var page []Scanner
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		scanners = append(scanners, page...)
+		return len(page), nil
+	})
+	return scanners, err
+}
+
+// GetScannerMetadata asks the scanner adapter registered as uuid about
+// itself. Harbor forwards the request to the adapter, so this also tells
+// whether the adapter is reachable.
+func (c *Client) GetScannerMetadata(ctx context.Context, uuid string) (*ScannerMetadata, error) {
+	if err := c.Require(CapScanners); err != nil {
+		return nil, err
+	}
+	var md ScannerMetadata
+	if err := c.get(ctx, "/scanners/"+url.PathEscape(uuid)+"/metadata", nil, &md); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
@@ -0,0 +1,40 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// ListSchedules returns the schedules of all periodic jobs. It needs Harbor
+// 2.7 or newer.
+func (c *Client) ListSchedules(ctx context.Context, opts *ListOptions) ([]ScheduleTask, error) {
+	if err := c.Require(CapSchedules); err != nil {
+		return nil, err
+	}
+	var schedules []ScheduleTask
+	err := c.list(ctx, "/schedules", nil, opts, func(body []byte) (int, error) {
+		var page []ScheduleTask
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		schedules = append(schedules, page...)
+		return len(page), nil
+	})
+	return schedules, err
+}
+
+// GetSchedulePaused reports whether the scheduler is paused for a job type.
+// Harbor only answers for jobType "all" so far.
+func (c *Client) GetSchedulePaused(ctx context.Context, jobType string) (bool, error) {
+	if err := c.Require(CapSchedules); err != nil {
+		return false, err
+	}
+	var status struct {
+		Paused bool `json:"paused"`
+	}
+	if err := c.get(ctx, "/schedules/"+url.PathEscape(jobType)+"/paused", nil, &status); err != nil {
+		return false, err
+	}
+	return status.Paused, nil
+}
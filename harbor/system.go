@@ -0,0 +1,54 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// GetStatistics returns the project and repository counts visible to the
+// user.
+func (c *Client) GetStatistics(ctx context.Context) (*Statistics, error) {
+	var s Statistics
+	if err := c.get(ctx, "/statistics", nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetSystemInfo returns the general information about the Harbor instance.
+func (c *Client) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var info SystemInfo
+	if err := c.get(ctx, "/systeminfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetSystemVolumes returns the size of the registry storage. Harbor 1.x
+// sends a single volume, 2.x a list.
+func (c *Client) GetSystemVolumes(ctx context.Context) ([]StorageInfo, error) {
+	var raw struct {
+		Storage json.RawMessage `json:"storage"`
+	}
+	if err := c.get(ctx, "/systeminfo/volumes", nil, &raw); err != nil {
+		return nil, err
+	}
+	var volumes []StorageInfo
+	if err := json.Unmarshal(raw.Storage, &volumes); err == nil {
+		return volumes, nil
+	}
+	var volume StorageInfo
+	if err := json.Unmarshal(raw.Storage, &volume); err != nil {
+		return nil, err
+	}
+	return []StorageInfo{volume}, nil
+}
+
+// GetHealth returns the health of Harbor and its components.
+func (c *Client) GetHealth(ctx context.Context) (*Health, error) {
+	var h Health
+	if err := c.get(ctx, "/health", nil, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
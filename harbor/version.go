@@ -0,0 +1,180 @@
+package harbor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Version is a Harbor release.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+var versionRE = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion parses the harbor_version field of /systeminfo, e.g.
+// "v2.8.4-6d8bd7a1".
+func ParseVersion(s string) (Version, error) {
+	m := versionRE.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("unable to parse harbor version %q", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// AtLeast reports whether v is major.minor or newer.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Names of the optional features a Harbor release may have.
+const (
+	// CapArtifacts is the artifact API of Harbor 2.0, replacing the tag
+	// centric repository API of 1.x.
+	CapArtifacts = "artifacts"
+	// CapQuotas is the /quotas API.
+	CapQuotas = "quotas"
+	// CapQuotaCount is the artifact count quota, dropped in Harbor 2.0.
+	CapQuotaCount = "quota_count"
+	// CapScanners is the pluggable scanner API.
+	CapScanners = "scanners"
+	// CapRetention is tag retention.
+	CapRetention = "retention"
+	// CapProxyCache is proxy-cache projects.
+	CapProxyCache = "proxy_cache"
+	// CapP2PPreheat is P2P preheating.
+	CapP2PPreheat = "p2p_preheat"
+	// CapSystemRobots is the /robots API for system and project robots.
+	CapSystemRobots = "system_robots"
+	// CapJobservice is the job service dashboard API with pools, workers
+	// and queues.
+	CapJobservice = "jobservice"
+	// CapSchedules is the /schedules API.
+	CapSchedules = "schedules"
+	// CapChartmuseum is the chart repository, removed in Harbor 2.8.
+	CapChartmuseum = "chartmuseum"
+	// CapNotary is content trust with notary, removed in Harbor 2.9.
+	CapNotary = "notary"
+)
+
+// Capabilities is the set of optional features of a Harbor instance.
+type Capabilities map[string]bool
+
+// Has reports whether the feature is available.
+func (c Capabilities) Has(name string) bool {
+	return c[name]
+}
+
+// Names returns the names of all known features, in a stable order.
+func (c Capabilities) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewCapabilities returns the features of Harbor version v. info, if not
+// nil, tells about the optional components the installation runs with.
+func NewCapabilities(v Version, info *SystemInfo) Capabilities {
+	withChartmuseum, withNotary := true, true
+	if info != nil {
+		withChartmuseum, withNotary = info.WithChartmuseum, info.WithNotary
+	}
+	return Capabilities{
+		CapArtifacts:    v.AtLeast(2, 0),
+		CapQuotas:       v.AtLeast(1, 9),
+		CapQuotaCount:   v.AtLeast(1, 9) && !v.AtLeast(2, 0),
+		CapScanners:     v.AtLeast(1, 10),
+		CapRetention:    v.AtLeast(1, 9),
+		CapProxyCache:   v.AtLeast(2, 1),
+		CapP2PPreheat:   v.AtLeast(2, 1),
+		CapSystemRobots: v.AtLeast(2, 2),
+		CapJobservice:   v.AtLeast(2, 7),
+		CapSchedules:    v.AtLeast(2, 7),
+		CapChartmuseum:  withChartmuseum && !v.AtLeast(2, 8),
+		CapNotary:       withNotary && !v.AtLeast(2, 9),
+	}
+}
+
+// DetectVersion reads the version from /systeminfo and sets Version,
+// VersionKnown and Capabilities. Harbor only shows its version to
+// authenticated users; when it is missing, unreadable or /systeminfo fails
+// the oldest release speaking the detected API is assumed, 1.0 or 2.0. It
+// only has the features every release of that API has and the components
+// /systeminfo tells about, if it answered.
+func (c *Client) DetectVersion(ctx context.Context) (*SystemInfo, error) {
+	info, err := c.GetSystemInfo(ctx)
+	if err != nil {
+		c.assumeVersion(&SystemInfo{})
+		return nil, err
+	}
+	v, err := ParseVersion(info.HarborVersion)
+	if err != nil {
+		c.assumeVersion(info)
+		return info, nil
+	}
+	c.Version = v
+	c.VersionKnown = true
+	c.Capabilities = NewCapabilities(v, info)
+	return info, nil
+}
+
+// assumeVersion sets the oldest release speaking the detected API.
+func (c *Client) assumeVersion(info *SystemInfo) {
+	c.Version = Version{Major: 1}
+	if c.APIPath == APIPathV2 {
+		c.Version = Version{Major: 2}
+	}
+	c.VersionKnown = false
+	c.Capabilities = NewCapabilities(c.Version, info)
+}
+
+// UnsupportedError is returned when the Harbor release lacks a feature.
+type UnsupportedError struct {
+	Capability string
+	Version    Version
+	// Assumed is set when the release is unknown and Version was assumed.
+	Assumed bool
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Assumed {
+		return fmt.Sprintf("harbor version unknown, assumed %s does not support %s", e.Version, e.Capability)
+	}
+	return fmt.Sprintf("harbor %s does not support %s", e.Version, e.Capability)
+}
+
+// IsUnsupported reports whether err is an UnsupportedError.
+func IsUnsupported(err error) bool {
+	_, ok := err.(*UnsupportedError)
+	return ok
+}
+
+// Require fails with an UnsupportedError if the feature is known to be
+// missing, or may be missing from an unknown release. Before DetectVersion
+// ran nothing is known and every feature is assumed to be there.
+func (c *Client) Require(capability string) error {
+	if c.Version == (Version{}) || c.Capabilities.Has(capability) {
+		return nil
+	}
+	return &UnsupportedError{
+		Capability: capability,
+		Version:    c.Version,
+		Assumed:    !c.VersionKnown,
+	}
+}
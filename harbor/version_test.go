@@ -0,0 +1,108 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func detect(t *testing.T, handler http.HandlerFunc) (*Client, error) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	c := NewClient(srv.URL, "", "", nil)
+	c.APIPath = APIPathV2
+	_, err := c.DetectVersion(context.Background())
+	return c, err
+}
+
+func TestDetectVersion(t *testing.T) {
+	c, err := detect(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"harbor_version": "v2.5.3-6d8bd7a1", "with_chartmuseum": true}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != (Version{2, 5, 3}) {
+		t.Errorf("version = %s, want 2.5.3", c.Version)
+	}
+	if !c.VersionKnown {
+		t.Error("version is unknown")
+	}
+	for _, name := range []string{CapProxyCache, CapChartmuseum} {
+		if err := c.Require(name); err != nil {
+			t.Errorf("Require(%s) = %s", name, err)
+		}
+	}
+	if err := c.Require(CapJobservice); !IsUnsupported(err) {
+		t.Errorf("Require(%s) = %v, want unsupported", CapJobservice, err)
+	}
+}
+
+// An unknown release is assumed to be the oldest of its API, so it only has
+// the features every release of the API has and the components /systeminfo
+// tells about.
+func TestDetectVersionUnknown(t *testing.T) {
+	hidden := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"with_chartmuseum": true}`))
+	}
+	failed := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}
+	// Every 2.x release has these.
+	v2 := []string{CapArtifacts, CapQuotas, CapScanners, CapRetention}
+	tests := []struct {
+		name    string
+		apiPath string
+		handler http.HandlerFunc
+		err     bool
+		version Version
+		has     []string
+	}{
+		{name: "hidden v2", apiPath: APIPathV2, handler: hidden, version: Version{2, 0, 0}, has: append(v2, CapChartmuseum)},
+		{name: "failed v2", apiPath: APIPathV2, handler: failed, err: true, version: Version{2, 0, 0}, has: v2},
+		{name: "hidden v1", apiPath: APIPathV1, handler: hidden, version: Version{1, 0, 0}, has: []string{CapChartmuseum}},
+		{name: "failed v1", apiPath: APIPathV1, handler: failed, err: true, version: Version{1, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(test.handler)
+			defer srv.Close()
+			c := NewClient(srv.URL, "", "", nil)
+			c.APIPath = test.apiPath
+			if _, err := c.DetectVersion(context.Background()); (err != nil) != test.err {
+				t.Fatalf("error = %v", err)
+			}
+			if c.Version != test.version {
+				t.Errorf("version = %s, want %s", c.Version, test.version)
+			}
+			if c.VersionKnown {
+				t.Error("version is known")
+			}
+			has := make(map[string]bool)
+			for _, name := range test.has {
+				has[name] = true
+			}
+			for _, name := range c.Capabilities.Names() {
+				err := c.Require(name)
+				if has[name] {
+					if err != nil {
+						t.Errorf("Require(%s) = %s", name, err)
+					}
+					continue
+				}
+				if u, ok := err.(*UnsupportedError); !ok || !u.Assumed {
+					t.Errorf("Require(%s) = %v, want unsupported by an assumed version", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireBeforeDetect(t *testing.T) {
+	c := NewClient("http://harbor", "", "", nil)
+	if err := c.Require(CapJobservice); err != nil {
+		t.Errorf("Require(%s) = %s", CapJobservice, err)
+	}
+}
@@ -14,10 +14,13 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"database/sql"
 	"fmt"
 
+	"github.com/c4po/harbor_exporter/harbor"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 
@@ -26,6 +29,8 @@ import (
 	_ "net/http/pprof"
 	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -37,10 +42,10 @@ import (
 	// "regexp"
 	"io/ioutil"
 	"strings"
+	"sync"
 	"time"
 
 	// kubernetes
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -49,20 +54,16 @@ const (
 	namespace = "harbor"
 )
 
-var (
-	up,
-	projectCount,
-	repoCount,
-	systemVolumes,
-	repositoriesPullCount,
-	repositoriesPushCount,
-	repositoriesTagsCount,
-	imagePullCount,
-	databaseHealth,
-	databaseConnections,
-	projectSize,
-	replicationStatus,
-	replicationTasks *prometheus.Desc
+var capabilityDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "exporter", "capability"),
+	"Whether the Harbor release has the named feature; collectors needing a missing one are skipped.",
+	[]string{"name"}, nil,
+)
+
+var versionKnownDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "exporter", "version_known"),
+	"Whether the Harbor release is known. When it is not, the oldest release of its API is assumed.",
+	nil, nil,
 )
 
 type promHTTPLogger struct {
@@ -76,11 +77,18 @@ func (l promHTTPLogger) Println(v ...interface{}) {
 // Exporter collects Consul stats from the given server and exports them using
 // the prometheus metrics package.
 type Exporter struct {
+	// ctx is the parent of every collector run started by Collect.
+	ctx        context.Context
 	client     HarborClient
 	opts       harborOpts
 	logger     log.Logger
 	kubeClient KubeClient
 	pg         Postgres
+	filter     projectFilter
+	collectors map[string]Collector
+	settings   map[string]collectorSettings
+	cache      *snapshotCache
+	up         *prometheus.Desc
 }
 
 type harborOpts struct {
@@ -90,12 +98,19 @@ type harborOpts struct {
 	password string
 	timeout  time.Duration
 	insecure bool
+	caFile   string
 	version  string
-	storage  string
+
+	// mode is the deployment mode, see discoverEnvironment.
+	mode        string
+	composeFile string
+	dsn         string
+	storage     string
 }
 
+// HarborClient is the Harbor API client shared by the collectors.
 type HarborClient struct {
-	client *http.Client
+	*harbor.Client
 	opts   harborOpts
 	logger log.Logger
 }
@@ -109,53 +124,96 @@ type KubeClient struct {
 type Postgres struct {
 	connStr         string
 	connPostgresStr string
+	// db and postgresDB are the shared pools for connStr and
+	// connPostgresStr, set by open.
+	db         *sql.DB
+	postgresDB *sql.DB
 }
 
-func (h HarborClient) request(endpoint string) []byte {
-	req, err := http.NewRequest("GET", h.opts.uri+h.opts.version+endpoint, nil)
-	if err != nil {
-		level.Error(h.logger).Log(err.Error())
-		return nil
+// open acquires the connection pools of p.
+func (p Postgres) open(opts poolOpts) (Postgres, error) {
+	var err error
+	if p.connStr != "" {
+		if p.db, err = pools.acquire("harbor", p.connStr, opts); err != nil {
+			return p, err
+		}
 	}
-	req.SetBasicAuth(h.opts.username, h.opts.password)
-
-	resp, err := h.client.Do(req)
-	if err != nil {
-		level.Error(h.logger).Log("msg", "Error handling request for "+endpoint, "err", err.Error())
-		return nil
+	if p.connPostgresStr != "" {
+		if p.postgresDB, err = pools.acquire("postgres", p.connPostgresStr, opts); err != nil {
+			p.close()
+			return p, err
+		}
 	}
-	defer resp.Body.Close()
+	return p, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		level.Error(h.logger).Log("msg", "Error handling request for "+endpoint, "http-statuscode", resp.Status)
-		return nil
+// close releases the connection pools acquired by open.
+func (p Postgres) close() {
+	if p.db != nil {
+		pools.release(p.db)
 	}
+	if p.postgresDB != nil {
+		pools.release(p.postgresDB)
+	}
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		level.Error(h.logger).Log("msg", "Error reading response of request for "+endpoint, "err", err.Error())
-		return nil
+// postgresParams are the pieces the Postgres connection strings are built
+// from. A dsn is used as is for both connections.
+type postgresParams struct {
+	host, port, user, password, dbname, sslmode string
+	dsn                                         string
+}
+
+// postgres returns the connection strings, or none at all when no database
+// is known.
+func (p postgresParams) postgres() Postgres {
+	if p.dsn != "" {
+		return Postgres{connStr: p.dsn, connPostgresStr: p.dsn}
+	}
+	if p.host == "" {
+		return Postgres{}
+	}
+	base := "user=" + p.user +
+		" host=" + p.host +
+		" port=" + p.port +
+		" sslmode=" + p.sslmode
+	if p.password != "" {
+		base += " password=" + p.password
+	}
+	return Postgres{
+		connStr:         base + " dbname=" + p.dbname,
+		connPostgresStr: base + " dbname=postgres",
 	}
-	return body
 }
 
-// NewExporter returns an initialized Exporter.
-func NewExporter(opts harborOpts, logger log.Logger) (*Exporter, error) {
+// newHarborClient validates the Harbor URL, sets up TLS and detects which
+// API version the server speaks.
+func newHarborClient(ctx context.Context, opts harborOpts, logger log.Logger) (HarborClient, error) {
 	uri := opts.uri
 	if !strings.Contains(uri, "://") {
 		uri = "http://" + uri
 	}
 	u, err := url.Parse(uri)
 	if err != nil {
-		return nil, fmt.Errorf("invalid harbor URL: %s", err)
+		return HarborClient{}, fmt.Errorf("invalid harbor URL: %s", err)
 	}
 	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
-		return nil, fmt.Errorf("invalid harbor URL: %s", uri)
+		return HarborClient{}, fmt.Errorf("invalid harbor URL: %s", uri)
 	}
+	opts.uri = strings.TrimRight(uri, "/")
 
 	rootCAs, err := x509.SystemCertPool()
 	if err != nil {
-		return nil, err
+		return HarborClient{}, err
+	}
+	if opts.caFile != "" {
+		pem, err := ioutil.ReadFile(opts.caFile)
+		if err != nil {
+			return HarborClient{}, fmt.Errorf("reading CA file: %s", err)
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return HarborClient{}, fmt.Errorf("no certificates found in CA file %s", opts.caFile)
+		}
 	}
 	tlsClientConfig := &tls.Config{
 		MinVersion: tls.VersionTLS12,
@@ -171,206 +229,157 @@ func NewExporter(opts harborOpts, logger log.Logger) (*Exporter, error) {
 
 	client := &http.Client{
 		Transport: transport,
+		Timeout:   opts.timeout,
 	}
 
-	resp, err := client.Get(uri + "/api/systeminfo")
-	if err == nil {
-		level.Info(logger).Log("msg", "check v1 with /api/systeminfo", "code", resp.StatusCode)
-		if resp.StatusCode == 200 {
-			opts.version = "/api"
-		}
-	} else {
-		level.Info(logger).Log("msg", "check v1 with /api/systeminfo", "err", err)
+	api := harbor.NewClient(opts.uri, opts.username, opts.password, client)
+	if err := api.DetectAPIPath(ctx); err != nil {
+		return HarborClient{}, err
 	}
-
-	resp, err = client.Get(uri + "/api/v2.0/systeminfo")
-	if err == nil {
-		level.Info(logger).Log("msg", "check v2 with /api/v2.0/systeminfo", "code", resp.StatusCode)
-		if resp.StatusCode == 200 {
-			opts.version = "/api/v2.0"
-		}
+	opts.version = api.APIPath
+	if info, err := api.DetectVersion(ctx); err != nil {
+		level.Warn(logger).Log("msg", "Unable to detect harbor version, skipping collectors needing a newer release", "assumed_version", api.Version, "err", err)
+	} else if !api.VersionKnown {
+		level.Warn(logger).Log("msg", "Harbor version hidden, skipping collectors needing a newer release", "uri", opts.uri, "path", api.APIPath, "assumed_version", api.Version)
 	} else {
-		level.Info(logger).Log("msg", "check v2 with /api/v2.0/systeminfo", "erro", err)
+		level.Info(logger).Log("msg", "Detected harbor", "uri", opts.uri, "path", api.APIPath, "harbor_version", info.HarborVersion)
 	}
 
-	if opts.version == "" {
-		return nil, fmt.Errorf("unable to determine harbor version")
-	}
+	return HarborClient{api, opts, logger}, nil
+}
 
-	hc := HarborClient{client, opts, logger}
+// initCollectors creates the given collectors for the exporter.
+func (e *Exporter) initCollectors(settings map[string]collectorSettings) error {
+	e.settings = settings
+	for name := range settings {
+		factory, ok := factories[name]
+		if !ok {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+		c, err := factory(e)
+		if isUnavailable(err) {
+			level.Info(e.logger).Log("msg", "Skipping collector", "collector", name, "reason", err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("creating %s collector: %s", name, err)
+		}
+		e.collectors[name] = c
+		level.Info(e.logger).Log("msg", "Enabled collector", "collector", name)
+	}
+	return nil
+}
 
-	// Init Prometheus Descriptors
-	up = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "up"),
+// newUpDesc returns the descriptor of the harbor_up metric.
+func newUpDesc(instance string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, instance, "up"),
 		"Was the last query of harbor successful.",
 		nil, nil,
 	)
-	projectCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "project_count_total"),
-		"projects number relevant to the user",
-		[]string{"type"}, nil,
-	)
-	repoCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "repo_count_total"),
-		"repositories number relevant to the user",
-		[]string{"type"}, nil,
-	)
-	systemVolumes = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "system_volumes_bytes"),
-		"Get system volume info (total/free size).",
-		[]string{"storage"}, nil,
-	)
-	repositoriesPullCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "repositories_pull_total"),
-		"Get public repositories which are accessed most.).",
-		[]string{"repo_name", "repo_id"}, nil,
-	)
-	repositoriesPushCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "repositories_push_total"),
-		"Get public repositories which are accessed most.).",
-		[]string{"repo_name", "repo_id"}, nil,
-	)
-	repositoriesTagsCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "repositories_tags_total"),
-		"Get public repositories which are accessed most.).",
-		[]string{"repo_name", "repo_id"}, nil,
-	)
-	imagePullCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "image_pull_count"),
-		"Get public image which are accessed most.).",
-		[]string{"repo_name", "repo_tag"}, nil,
-	)
-	databaseHealth = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "database_health"),
-		"Get if database alive.).",
-		[]string{}, nil,
-	)
-	databaseConnections = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "database_connections"),
-		"Get Database connections count.).",
-		[]string{}, nil,
-	)
-	projectSize = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "project_size"),
-		"Get Project all image size sum).",
-		[]string{"project_name"}, nil,
-	)
-	replicationStatus = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "replication_status"),
-		"Get status of the last execution of this replication policy: Succeed = 1, any other status = 0.",
-		[]string{"repl_pol_name"}, nil,
-	)
-	replicationTasks = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, opts.instance, "replication_tasks"),
-		"Get number of replication tasks, with various results, in the latest execution of this replication policy.",
-		[]string{"repl_pol_name", "result"}, nil,
-	)
+}
 
-	// 初始化 kube-client
-	var kubeClient KubeClient
-	var postgres Postgres
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		panic(err.Error())
-	}
-	kubeClient.config = config
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
-	kubeClient.client = clientset
-	// 得到exporter所在的命名空间
-	data, err := ioutil.ReadFile("/run/secrets/kubernetes.io/serviceaccount/namespace")
+// NewExporter returns an initialized Exporter. Settings in conf take
+// precedence over opts. ctx bounds talking to Harbor while setting up.
+func NewExporter(ctx context.Context, opts harborOpts, conf *Config, logger log.Logger) (*Exporter, error) {
+	hc, err := newHarborClient(ctx, conf.Harbor.apply(opts), logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "Error to get namespace", "err", err)
+		return nil, err
 	}
-	kubeClient.namespace = string(data)
+	opts = conf.Deployment.apply(hc.opts)
 
-	configmapList, err := clientset.CoreV1().ConfigMaps(kubeClient.namespace).List(metav1.ListOptions{})
+	env, err := discoverEnvironment(opts, logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "Error getting storage location", "err", err)
-	}
-	for _, configMap := range configmapList.Items {
-		// 得到storage的存储位置
-		if strings.Contains(configMap.Name, "harbor-registry") {
-			registryYaml := configMap.Data["config.yml"]
-			configSplit := strings.Split(registryYaml, ":")
-			for i, val := range configSplit {
-				if strings.Contains(val, "rootdirectory") {
-					opts.storage = configSplit[i+1]
-					break
-				}
-			}
-			if err != nil {
-				level.Error(logger).Log("msg", "Error getting storage location", "err", err)
-			}
-		} else if strings.Contains(configMap.Name, "harbor-core") {
-			// 得到pg相关信息
-			postgres.connStr = "user=" + configMap.Data["POSTGRESQL_USERNAME"] +
-				" host=" + configMap.Data["POSTGRESQL_HOST"] +
-				" port=" + configMap.Data["POSTGRESQL_PORT"] +
-				" sslmode=" + configMap.Data["POSTGRESQL_SSLMODE"]
-			postgres.connPostgresStr = postgres.connStr + " dbname=postgres"
-			postgres.connStr += " dbname=" + configMap.Data["POSTGRESQL_DATABASE"]
-		}
+		return nil, err
 	}
+	opts.storage = env.storage
 
-	secretList, err := clientset.CoreV1().Secrets(kubeClient.namespace).List(metav1.ListOptions{})
+	pg, err := conf.Database.apply(env.pg).postgres().open(conf.Database.poolOpts())
 	if err != nil {
-		level.Error(logger).Log("msg", "Error getting postgres password", "err", err)
-	}
-	for _, secret := range secretList.Items {
-		if strings.Contains(secret.Name, "harbor-database") {
-			postgres.connStr += " password=" + string(secret.Data["POSTGRES_PASSWORD"])
-			postgres.connPostgresStr += " password=" + string(secret.Data["POSTGRES_PASSWORD"])
-			break
-		}
+		return nil, fmt.Errorf("opening database: %s", err)
 	}
 
 	// Init our exporter.
-	return &Exporter{
+	e := &Exporter{
+		ctx:        context.Background(),
 		client:     hc,
 		opts:       opts,
 		logger:     logger,
-		kubeClient: kubeClient,
-		pg:         postgres,
-	}, nil
+		kubeClient: env.kubeClient,
+		pg:         pg,
+		filter:     conf.projectFilter,
+		collectors: make(map[string]Collector),
+		cache:      newSnapshotCache(),
+		up:         newUpDesc(opts.instance),
+	}
+	if err := e.initCollectors(enabledCollectors(conf)); err != nil {
+		e.close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// close releases the resources held by the exporter. Collectors still running
+// fail from then on.
+func (e *Exporter) close() {
+	e.pg.close()
 }
 
-// Describe describes all the metrics ever exported by the Consul exporter. It
+// Describe describes all the metrics ever exported by the harbor exporter. It
 // implements prometheus.Collector.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- up
-	ch <- projectCount
-	ch <- repoCount
-	ch <- systemVolumes
-	ch <- repositoriesPullCount
-	ch <- repositoriesPushCount
-	ch <- repositoriesTagsCount
-	ch <- imagePullCount
-	ch <- databaseHealth
-	ch <- databaseConnections
-	ch <- projectSize
-	ch <- replicationStatus
-	ch <- replicationTasks
+	ch <- e.up
+	ch <- scrapeDurationDesc
+	ch <- scrapeSuccessDesc
+	ch <- lastCollectionDesc
+	ch <- capabilityDesc
+	ch <- versionKnownDesc
 }
 
-// Collect fetches the stats from configured Consul location and delivers them
-// as Prometheus metrics. It implements prometheus.Collector.
+// Collect runs every enabled collector concurrently and delivers the results
+// as Prometheus metrics. Collectors refreshed in the background are served
+// from their last snapshot instead. It implements prometheus.Collector.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	ok := e.collectStatisticsMetric(ch)
-	ok = e.collectSystemVolumesMetric(ch) && ok
-	ok = e.collectRepositoriesMetric(ch, e.opts.version) && ok
-	ok = e.collectDatabaseMetric(ch) && ok
-	ok = e.collectReplicationsMetric(ch) && ok
+	var (
+		wg  sync.WaitGroup
+		mtx sync.Mutex
+		ok  = true
+	)
+	wg.Add(len(e.collectors))
+	for name, c := range e.collectors {
+		go func(name string, c Collector) {
+			defer wg.Done()
+			var success bool
+			if settings := e.settings[name]; settings.interval > 0 {
+				success = e.cache.collect(name, ch)
+			} else {
+				success = execute(e.ctx, name, settings.timeout, c, ch, e.logger)
+			}
+			if !success {
+				mtx.Lock()
+				ok = false
+				mtx.Unlock()
+			}
+		}(name, c)
+	}
+	wg.Wait()
+
+	for _, name := range e.client.Capabilities.Names() {
+		var v float64
+		if e.client.Capabilities.Has(name) {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(capabilityDesc, prometheus.GaugeValue, v, name)
+	}
+	ch <- prometheus.MustNewConstMetric(versionKnownDesc, prometheus.GaugeValue, boolToFloat(e.client.VersionKnown))
 
 	if ok {
 		ch <- prometheus.MustNewConstMetric(
-			up, prometheus.GaugeValue, 1.0,
+			e.up, prometheus.GaugeValue, 1.0,
 		)
 	} else {
 		ch <- prometheus.MustNewConstMetric(
-			up, prometheus.GaugeValue, 0.0,
+			e.up, prometheus.GaugeValue, 0.0,
 		)
 	}
 }
@@ -383,6 +392,7 @@ func main() {
 	var (
 		listenAddress = kingpin.Flag("web.listen-address", "Address to listen on for web interface and telemetry.").Default(":9107").String()
 		metricsPath   = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
+		configFile    = kingpin.Flag("config.file", "Path to the YAML config file. Reloaded on SIGHUP or POST /-/reload.").Default("").String()
 
 		opts = harborOpts{}
 	)
@@ -390,8 +400,12 @@ func main() {
 	kingpin.Flag("harbor.server", "HTTP API address of a harbor server or agent. (prefix with https:// to connect over HTTPS)").Envar("HARBOR_URI").Default("http://localhost:8500").StringVar(&opts.uri)
 	kingpin.Flag("harbor.username", "username").Envar("HARBOR_USERNAME").Default("admin").StringVar(&opts.username)
 	kingpin.Flag("harbor.password", "password").Envar("HARBOR_PASSWORD").Default("password").StringVar(&opts.password)
-	kingpin.Flag("harbor.timeout", "Timeout on HTTP requests to the harbor API.").Default("500ms").DurationVar(&opts.timeout)
+	kingpin.Flag("harbor.timeout", "Timeout on HTTP requests to the harbor API, 0 for none.").Default("5s").DurationVar(&opts.timeout)
 	kingpin.Flag("harbor.insecure", "Disable TLS host verification.").Default("false").BoolVar(&opts.insecure)
+	kingpin.Flag("deployment.mode", "How Harbor is deployed, one of "+strings.Join(deploymentModes, ", ")+". Decides where the database settings and the registry storage are looked up.").Default(modeKubernetes).EnumVar(&opts.mode, deploymentModes...)
+	kingpin.Flag("compose.harbor-config", "Path to the harbor.yml of a docker-compose installation, read in compose mode.").Default("/etc/harbor/harbor.yml").StringVar(&opts.composeFile)
+	kingpin.Flag("database.dsn", "Postgres connection string of the Harbor database, replacing the discovered settings.").Envar("HARBOR_DATABASE_DSN").Default("").StringVar(&opts.dsn)
+	kingpin.Flag("storage.path", "Path of the registry storage, replacing the discovered one. In kubernetes mode it is looked up inside the registry pod.").Default("").StringVar(&opts.storage)
 
 	promlogConfig := &promlog.Config{}
 	flag.AddFlags(kingpin.CommandLine, promlogConfig)
@@ -402,13 +416,23 @@ func main() {
 	level.Info(logger).Log("msg", "Starting harbor_exporter", "version", version.Info())
 	level.Info(logger).Log("build_context", version.BuildContext())
 
-	exporter, err := NewExporter(opts, logger)
-	if err != nil {
+	exporter := newReloadableExporter(*configFile, opts, logger)
+	if err := exporter.reload(); err != nil {
 		level.Error(logger).Log("msg", "Error creating the exporter", "err", err)
 		os.Exit(1)
 	}
 	prometheus.MustRegister(exporter)
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := exporter.reload(); err != nil {
+				level.Error(logger).Log("msg", "Error reloading config", "err", err)
+			}
+		}
+	}()
+
 	http.Handle(*metricsPath,
 		promhttp.InstrumentMetricHandler(
 			prometheus.DefaultRegisterer,
@@ -422,12 +446,29 @@ func main() {
 			),
 		),
 	)
+	http.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
+		probeHandler(w, r, exporter.config(), logger)
+	})
+	http.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprintf(w, "This endpoint requires a POST request.\n")
+			return
+		}
+		if err := exporter.reload(); err != nil {
+			level.Error(logger).Log("msg", "Error reloading config", "err", err)
+			http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "OK")
+	})
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>Harbor Exporter</title></head>
             <body>
             <h1>harbor Exporter</h1>
              <p><a href='` + *metricsPath + `'>Metrics</a></p>
+             <p><a href='/probe?target=https://harbor.example.com&module=default'>Probe a Harbor</a></p>
             <h2>Build</h2>
             <pre>` + version.Info() + ` ` + version.BuildContext() + `</pre>
             </body>
@@ -442,9 +483,29 @@ func main() {
 		fmt.Fprintf(w, "OK")
 	})
 
+	// On SIGTERM stop serving, then close the database sessions so that a
+	// restarting exporter never holds connections Harbor needs.
+	server := &http.Server{Addr: *listenAddress}
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		<-term
+		level.Info(logger).Log("msg", "Received shutdown signal, exiting gracefully")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			level.Error(logger).Log("msg", "Error shutting down HTTP server", "err", err)
+		}
+		exporter.shutdown()
+		pools.closeAll()
+		close(done)
+	}()
+
 	level.Info(logger).Log("msg", "Listening on address", "address", *listenAddress)
-	if err := http.ListenAndServe(*listenAddress, nil); err != nil {
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		level.Error(logger).Log("msg", "Error starting HTTP server", "err", err)
 		os.Exit(1)
 	}
+	<-done
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Without a deadline on the context, --harbor.timeout still keeps startup
+// from hanging on an unresponsive Harbor.
+func TestHarborClientTimeout(t *testing.T) {
+	srv := hangingHarbor()
+	defer srv.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := newHarborClient(context.Background(), harborOpts{uri: srv.URL, timeout: 100 * time.Millisecond}, log.NewNopLogger())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("newHarborClient against a hanging Harbor succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("newHarborClient ignored --harbor.timeout")
+	}
+}
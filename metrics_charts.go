@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("charts", defaultEnabled, newChartsCollector)
+}
+
+type chartsCollector struct {
+	client     HarborClient
+	filter     projectFilter
+	logger     log.Logger
+	healthy    *prometheus.Desc
+	up         *prometheus.Desc
+	charts     *prometheus.Desc
+	versions   *prometheus.Desc
+	deprecated *prometheus.Desc
+}
+
+func newChartsCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapChartmuseum); err != nil {
+		return nil, err
+	}
+	return &chartsCollector{
+		client: e.client,
+		filter: e.filter,
+		logger: e.logger,
+		healthy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "chartrepo_healthy"),
+			"Whether chartmuseum reports itself healthy.",
+			nil, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "chartrepo_up"),
+			"Whether the chart repository of the project could be listed.",
+			[]string{"project"}, nil,
+		),
+		charts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_charts"),
+			"Number of Helm charts in the chart repository of the project.",
+			[]string{"project"}, nil,
+		),
+		versions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_chart_versions"),
+			"Number of chart versions in the chart repository of the project.",
+			[]string{"project"}, nil,
+		),
+		deprecated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_charts_deprecated"),
+			"Number of deprecated Helm charts in the chart repository of the project.",
+			[]string{"project"}, nil,
+		),
+	}, nil
+}
+
+func (c *chartsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	// An unhealthy chartmuseum may answer with an error; that is what
+	// chartrepo_healthy is there to show.
+	healthy, err := c.client.GetChartRepoHealth(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if harbor.IsForbidden(err) {
+		return fmt.Errorf("error retrieving chart repository health: %s", err)
+	}
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "Chart repository not healthy", "err", err)
+		healthy = false
+	}
+	ch <- prometheus.MustNewConstMetric(c.healthy, prometheus.GaugeValue, boolToFloat(healthy))
+
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		charts, err := c.client.ListCharts(ctx, project.Name)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if harbor.IsForbidden(err) {
+			return fmt.Errorf("error retrieving charts of %s: %s", project.Name, err)
+		}
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "Unable to list charts", "project", project.Name, "err", err)
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, project.Name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, project.Name)
+
+		var versions, deprecated int64
+		for _, chart := range charts {
+			versions += chart.TotalVersions
+			if chart.Deprecated {
+				deprecated++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.charts, prometheus.GaugeValue, float64(len(charts)), project.Name)
+		ch <- prometheus.MustNewConstMetric(c.versions, prometheus.GaugeValue, float64(versions), project.Name)
+		ch <- prometheus.MustNewConstMetric(c.deprecated, prometheus.GaugeValue, float64(deprecated), project.Name)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// chartsHarbor serves a chart repository whose health check answers with
+// status, and the charts of the library project.
+func chartsHarbor(status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chartrepo/health":
+			if status != http.StatusOK {
+				http.Error(w, `{"errors": [{"code": "UNKNOWN", "message": "chartmuseum down"}]}`, status)
+				return
+			}
+			w.Write([]byte(`{"healthy": true}`))
+		case "/projects":
+			w.Write([]byte(`[{"project_id": 1, "name": "library"}]`))
+		case "/chartrepo/library/charts":
+			w.Write([]byte(`[
+				{"name": "nginx", "total_versions": 3, "latest_version": "1.2.0"},
+				{"name": "legacy", "total_versions": 1, "latest_version": "0.1.0", "deprecated": true}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestChartsHealth(t *testing.T) {
+	for _, test := range []struct {
+		status  int
+		healthy float64
+		err     bool
+	}{
+		{status: http.StatusOK, healthy: 1},
+		{status: http.StatusServiceUnavailable, healthy: 0},
+		{status: http.StatusInternalServerError, healthy: 0},
+		{status: http.StatusUnauthorized, err: true},
+		{status: http.StatusForbidden, err: true},
+	} {
+		hc, srv := testHarbor(chartsHarbor(test.status))
+		c, err := newChartsCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples, err := collect(t, c)
+		srv.Close()
+		if test.err {
+			if err == nil {
+				t.Errorf("%d: no error", test.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s", test.status, err)
+			continue
+		}
+		if v, ok := find(samples, "harbor_chartrepo_healthy"); !ok || v != test.healthy {
+			t.Errorf("%d: chartrepo_healthy = %v (found %v), want %v", test.status, v, ok, test.healthy)
+		}
+		// The charts are listed whether or not the health check passed.
+		if v, _ := find(samples, "harbor_project_chart_versions", "project", "library"); v != 4 {
+			t.Errorf("%d: project_chart_versions = %v, want 4", test.status, v)
+		}
+		if v, _ := find(samples, "harbor_project_charts_deprecated", "project", "library"); v != 1 {
+			t.Errorf("%d: project_charts_deprecated = %v, want 1", test.status, v)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 
-	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -11,29 +11,55 @@ var (
 	queryConnections = `select count(1) from pg_stat_activity;`
 )
 
-func (e *Exporter) collectDatabaseMetric(ch chan<- prometheus.Metric) bool {
-	db, err := sql.Open("postgres", e.pg.connPostgresStr)
-	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error connect to db", "err", err)
-	}
-	defer db.Close()
+func init() {
+	registerCollector("database", defaultEnabled, newDatabaseCollector)
+}
 
-	health := 1.0
-	err = db.Ping()
-	if err != nil {
-		health = 0.0
+type databaseCollector struct {
+	pg                  Postgres
+	databaseHealth      *prometheus.Desc
+	databaseConnections *prometheus.Desc
+}
+
+func newDatabaseCollector(e *Exporter) (Collector, error) {
+	if e.pg.connPostgresStr == "" {
+		return nil, &unavailableError{"no database configured"}
 	}
+	return &databaseCollector{
+		pg: e.pg,
+		databaseHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "database_health"),
+			"Get if database alive.).",
+			[]string{}, nil,
+		),
+		databaseConnections: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "database_connections"),
+			"Get Database connections count.).",
+			[]string{}, nil,
+		),
+	}, nil
+}
 
-	var conns float64
-	res := db.QueryRow(queryConnections)
-	res.Scan(&conns)
+func (c *databaseCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	db := c.pg.postgresDB
 
+	if err := db.PingContext(ctx); err != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.databaseHealth, prometheus.GaugeValue, 0,
+		)
+		return fmt.Errorf("error pinging database: %s", err)
+	}
 	ch <- prometheus.MustNewConstMetric(
-		databaseHealth, prometheus.GaugeValue, health,
+		c.databaseHealth, prometheus.GaugeValue, 1,
 	)
+
+	var conns float64
+	if err := db.QueryRowContext(ctx, queryConnections).Scan(&conns); err != nil {
+		return fmt.Errorf("error counting database connections: %s", err)
+	}
 	ch <- prometheus.MustNewConstMetric(
-		databaseConnections, prometheus.GaugeValue, conns,
+		c.databaseConnections, prometheus.GaugeValue, conns,
 	)
 
-	return true
+	return nil
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// The GC job logs what it deleted, or would delete on a dry run, e.g.
+	// "3 blobs and 1 manifests are actually deleted" and "The GC job actual
+	// frees up 11 MB space.".
+	gcDeletedRE   = regexp.MustCompile(`(\d+) blobs and (\d+) manifests are actually deleted`)
+	gcEligibleRE  = regexp.MustCompile(`(\d+) blobs and (\d+) manifests eligible for deletion`)
+	gcFreedRE     = regexp.MustCompile(`(?i)actual frees up ([\d.]+)\s*([kmgtp]?i?b)`)
+	gcCouldFreeRE = regexp.MustCompile(`(?i)could free up ([\d.]+)\s*([kmgtp]?i?b)`)
+)
+
+func init() {
+	registerCollector("gc", defaultEnabled, newGCCollector)
+}
+
+// gcFreed is what a GC run freed according to its log.
+type gcFreed struct {
+	blobs, manifests, bytes float64
+	found, foundBytes       bool
+}
+
+type gcCollector struct {
+	client         HarborClient
+	logger         log.Logger
+	status         *prometheus.Desc
+	startTime      *prometheus.Desc
+	endTime        *prometheus.Desc
+	duration       *prometheus.Desc
+	dryRun         *prometheus.Desc
+	freedBlobs     *prometheus.Desc
+	freedManifests *prometheus.Desc
+	freedBytes     *prometheus.Desc
+	nextRun        *prometheus.Desc
+
+	// The log of a finished run does not change, so it is parsed once.
+	mtx      sync.Mutex
+	freedID  int64
+	freedLog gcFreed
+}
+
+func newGCCollector(e *Exporter) (Collector, error) {
+	return &gcCollector{
+		client: e.client,
+		logger: e.logger,
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_status"),
+			"Status of the last garbage collection run, 1 for the current status.",
+			[]string{"status"}, nil,
+		),
+		startTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_start_timestamp_seconds"),
+			"Start time of the last garbage collection run.",
+			nil, nil,
+		),
+		endTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_end_timestamp_seconds"),
+			"End time of the last garbage collection run, once finished.",
+			nil, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_duration_seconds"),
+			"Duration of the last garbage collection run, once finished.",
+			nil, nil,
+		),
+		dryRun: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_dry_run"),
+			"Whether the last garbage collection run was a dry run.",
+			nil, nil,
+		),
+		freedBlobs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_freed_blobs"),
+			"Blobs deleted by the last finished garbage collection run, or eligible for deletion on a dry run.",
+			nil, nil,
+		),
+		freedManifests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_freed_manifests"),
+			"Manifests deleted by the last finished garbage collection run, or eligible for deletion on a dry run.",
+			nil, nil,
+		),
+		freedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_last_run_freed_bytes"),
+			"Space freed by the last finished garbage collection run, or the estimate of a dry run, as rounded in the job log.",
+			nil, nil,
+		),
+		nextRun: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "gc_next_run_timestamp_seconds"),
+			"Next scheduled garbage collection run.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func (c *gcCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	schedule, err := c.client.GetGCSchedule(ctx)
+	if err != nil && !harbor.IsNotFound(err) {
+		return fmt.Errorf("error retrieving gc schedule: %s", err)
+	}
+	if schedule != nil && schedule.Schedule != nil && !schedule.Schedule.NextScheduledTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.nextRun, prometheus.GaugeValue, unixSeconds(schedule.Schedule.NextScheduledTime),
+		)
+	}
+
+	// Not every release sorts as asked, so look for the latest run among
+	// the first few.
+	runs, err := c.client.ListGCHistory(ctx, &harbor.ListOptions{Sort: "-creation_time", Limit: 10})
+	if err != nil {
+		return fmt.Errorf("error retrieving gc history: %s", err)
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	last := runs[0]
+	for _, run := range runs[1:] {
+		if run.CreationTime.After(last.CreationTime.Time) {
+			last = run
+		}
+	}
+
+	status := jobStatus(last.JobStatus)
+	for _, s := range jobStatuses {
+		var v float64
+		if s == status {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, v, s)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.startTime, prometheus.GaugeValue, unixSeconds(last.CreationTime),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.dryRun, prometheus.GaugeValue, boolToFloat(last.DryRun()),
+	)
+	if !jobFinished(status) {
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.endTime, prometheus.GaugeValue, unixSeconds(last.UpdateTime),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.duration, prometheus.GaugeValue, last.UpdateTime.Sub(last.CreationTime.Time).Seconds(),
+	)
+
+	freed, err := c.freed(ctx, last)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "Unable to read gc log", "id", last.ID, "err", err)
+		return nil
+	}
+	if freed.found {
+		ch <- prometheus.MustNewConstMetric(c.freedBlobs, prometheus.GaugeValue, freed.blobs)
+		ch <- prometheus.MustNewConstMetric(c.freedManifests, prometheus.GaugeValue, freed.manifests)
+	}
+	if freed.foundBytes {
+		ch <- prometheus.MustNewConstMetric(c.freedBytes, prometheus.GaugeValue, freed.bytes)
+	}
+	return nil
+}
+
+// freed returns what run freed, reading its log unless it was read before.
+func (c *gcCollector) freed(ctx context.Context, run harbor.GCHistory) (gcFreed, error) {
+	c.mtx.Lock()
+	if c.freedID == run.ID {
+		defer c.mtx.Unlock()
+		return c.freedLog, nil
+	}
+	c.mtx.Unlock()
+
+	text, err := c.client.GetGCLog(ctx, run.ID)
+	if err != nil {
+		return gcFreed{}, err
+	}
+	freed := parseGCLog(text)
+
+	c.mtx.Lock()
+	c.freedID, c.freedLog = run.ID, freed
+	c.mtx.Unlock()
+	return freed, nil
+}
+
+// parseGCLog finds the deleted blobs and manifests and the freed space in a
+// GC job log. The numbers of the actual deletion win over the estimate.
+func parseGCLog(text string) gcFreed {
+	var freed gcFreed
+	for _, re := range []*regexp.Regexp{gcDeletedRE, gcEligibleRE} {
+		if m := lastMatch(re, text); m != nil {
+			freed.blobs, _ = strconv.ParseFloat(m[1], 64)
+			freed.manifests, _ = strconv.ParseFloat(m[2], 64)
+			freed.found = true
+			break
+		}
+	}
+	for _, re := range []*regexp.Regexp{gcFreedRE, gcCouldFreeRE} {
+		if m := lastMatch(re, text); m != nil {
+			size, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			freed.bytes = size * unitBytes(m[2])
+			freed.foundBytes = true
+			break
+		}
+	}
+	return freed
+}
+
+func lastMatch(re *regexp.Regexp, text string) []string {
+	matches := re.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[len(matches)-1]
+}
+
+// unitBytes returns the size of a unit as Harbor writes it, which counts a
+// MB as 1024*1024 bytes.
+func unitBytes(unit string) float64 {
+	if unit == "" {
+		return 1
+	}
+	switch strings.ToLower(unit)[:1] {
+	case "k":
+		return 1 << 10
+	case "m":
+		return 1 << 20
+	case "g":
+		return 1 << 30
+	case "t":
+		return 1 << 40
+	case "p":
+		return 1 << 50
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+// gcLog1 is the tail of a Harbor 1.10 GC job, which runs the registry's
+// garbage-collect and does not tell the freed space.
+const gcLog1 = `2020-03-12T02:00:01Z [INFO] [/jobservice/job/impl/gc/job.go:134]: start to run gc in job.
+2020-03-12T02:00:01Z [INFO] [/jobservice/job/impl/gc/job.go:165]: start to run registry garbage collection...
+library/nginx
+library/nginx: marking manifest sha256:b0ad43f7ee5edbc0effbc14645ae7055e21bc1973aee5150745632a24a752661
+library/nginx: marking blob sha256:c58e8a1ba2d2d4b0e0b1f13d3d2a1f0b72cd3b5fd8dcbcac4c2c8e5c9fd1d1e5
+
+2 blobs marked, 5 blobs and 1 manifests eligible for deletion
+blob eligible for deletion: sha256:0a57a2b8cb6c1e3f4ae6bfa1f0e1c9c66ffd4b31c1aadf5a1d3c2f1a6d0e0b12
+2020-03-12T02:00:04Z [INFO] [/jobservice/job/impl/gc/job.go:178]: GC results: status: true, message: Garbage collection complete, start time: 2020-03-12 02:00:01, end time: 2020-03-12 02:00:04
+2020-03-12T02:00:04Z [INFO] [/jobservice/job/impl/gc/job.go:152]: success to run gc in job.`
+
+// gcLog2 is the tail of a Harbor 2.x GC job.
+const gcLog2 = `2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:145]: start to run gc in job.
+2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:280]: 12 blobs and 3 manifests eligible for deletion
+2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:281]: The GC could free up 60 MB space, the size is a rough estimate.
+2023-05-02T02:00:03Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:412]: 11 blobs and 3 manifests are actually deleted
+2023-05-02T02:00:03Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:413]: The GC job actual frees up 55 MB space.
+2023-05-02T02:00:03Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:186]: success to run gc in job.`
+
+// gcLogDryRun is a Harbor 2.x dry run, which only estimates.
+const gcLogDryRun = `2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:145]: start to run gc in job.
+2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:280]: 7 blobs and 2 manifests eligible for deletion
+2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:281]: The GC could free up 1.5 GB space, the size is a rough estimate.
+2023-05-02T02:00:00Z [INFO] [/jobservice/job/impl/gc/garbage_collection.go:169]: dry run mode, skip the deletion.`
+
+func TestParseGCLog(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want gcFreed
+	}{
+		{
+			name: "1.x",
+			text: gcLog1,
+			want: gcFreed{blobs: 5, manifests: 1, found: true},
+		},
+		{
+			name: "2.x",
+			text: gcLog2,
+			want: gcFreed{blobs: 11, manifests: 3, bytes: 55 << 20, found: true, foundBytes: true},
+		},
+		{
+			name: "dry run",
+			text: gcLogDryRun,
+			want: gcFreed{blobs: 7, manifests: 2, bytes: 1.5 * (1 << 30), found: true, foundBytes: true},
+		},
+		{
+			name: "last run wins",
+			text: gcLog2 + "\n11 blobs and 0 manifests are actually deleted\nThe GC job actual frees up 10 KB space.",
+			want: gcFreed{blobs: 11, manifests: 0, bytes: 10 << 10, found: true, foundBytes: true},
+		},
+		{
+			name: "unmatched",
+			text: "2023-05-02T02:00:00Z [ERROR] [/jobservice/job/impl/gc/garbage_collection.go:160]: failed to get the registry client: connection refused",
+			want: gcFreed{},
+		},
+		{
+			name: "empty",
+			want: gcFreed{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseGCLog(test.text); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnitBytes(t *testing.T) {
+	tests := []struct {
+		unit string
+		want float64
+	}{
+		{"", 1},
+		{"B", 1},
+		{"b", 1},
+		{"KB", 1 << 10},
+		{"KiB", 1 << 10},
+		{"kb", 1 << 10},
+		{"MB", 1 << 20},
+		{"MiB", 1 << 20},
+		{"GB", 1 << 30},
+		{"TB", 1 << 40},
+		{"PB", 1 << 50},
+	}
+	for _, test := range tests {
+		if got := unitBytes(test.unit); got != test.want {
+			t.Errorf("unitBytes(%q) = %v, want %v", test.unit, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxHealthErrorLength caps the error label of the component error info.
+const maxHealthErrorLength = 256
+
+func init() {
+	registerCollector("health", defaultEnabled, newHealthCollector)
+}
+
+type healthCollector struct {
+	client    HarborClient
+	healthy   *prometheus.Desc
+	errorInfo *prometheus.Desc
+}
+
+func newHealthCollector(e *Exporter) (Collector, error) {
+	return &healthCollector{
+		client: e.client,
+		healthy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "component_healthy"),
+			"Whether the Harbor component is healthy, as reported by /health.",
+			[]string{"component"}, nil,
+		),
+		errorInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "component_error_info"),
+			"Why an unhealthy Harbor component is unhealthy, always 1.",
+			[]string{"component", "error"}, nil,
+		),
+	}, nil
+}
+
+func (c *healthCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	health, err := c.client.GetHealth(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving health: %s", err)
+	}
+	for _, component := range health.Components {
+		ch <- prometheus.MustNewConstMetric(
+			c.healthy, prometheus.GaugeValue, boolToFloat(component.Healthy()), component.Name,
+		)
+		if component.Healthy() {
+			continue
+		}
+		msg := component.Error
+		if len(msg) > maxHealthErrorLength {
+			msg = msg[:maxHealthErrorLength] + "..."
+		}
+		msg = strings.ToValidUTF8(msg, "?")
+		ch <- prometheus.MustNewConstMetric(
+			c.errorInfo, prometheus.GaugeValue, 1, component.Name, msg,
+		)
+	}
+	return nil
+}
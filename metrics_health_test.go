@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestHealth(t *testing.T) {
+	longError := strings.Repeat("x", 2*maxHealthErrorLength)
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status": "unhealthy", "components": [
+			{"name": "core", "status": "healthy"},
+			{"name": "redis", "status": "unhealthy", "error": "dial tcp redis:6379: connection refused"},
+			{"name": "registry", "status": "unhealthy", "error": "` + longError + `"}
+		]}`))
+	})
+	defer srv.Close()
+	c, err := newHealthCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for component, want := range map[string]float64{"core": 1, "redis": 0, "registry": 0} {
+		if v, ok := find(samples, "harbor_component_healthy", "component", component); !ok || v != want {
+			t.Errorf("component_healthy{component=%q} = %v (found %v), want %v", component, v, ok, want)
+		}
+	}
+	if _, ok := find(samples, "harbor_component_error_info", "component", "redis", "error", "dial tcp redis:6379: connection refused"); !ok {
+		t.Error("error of redis missing")
+	}
+	if _, ok := find(samples, "harbor_component_error_info", "component", "core"); ok {
+		t.Error("error info exported for a healthy component")
+	}
+	// Long errors are cut.
+	truncated := longError[:maxHealthErrorLength] + "..."
+	if _, ok := find(samples, "harbor_component_error_info", "component", "registry", "error", truncated); !ok {
+		t.Error("error of registry not truncated")
+	}
+}
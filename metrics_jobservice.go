@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("jobservice", defaultEnabled, newJobserviceCollector)
+}
+
+type jobserviceCollector struct {
+	client       HarborClient
+	concurrency  *prometheus.Desc
+	workers      *prometheus.Desc
+	queueDepth   *prometheus.Desc
+	queueLatency *prometheus.Desc
+	queuePaused  *prometheus.Desc
+}
+
+func newJobserviceCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapJobservice); err != nil {
+		return nil, err
+	}
+	return &jobserviceCollector{
+		client: e.client,
+		concurrency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "jobservice_pool_concurrency"),
+			"Number of workers of the jobservice worker pool.",
+			[]string{"pool", "host"}, nil,
+		),
+		workers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "jobservice_workers"),
+			"Number of jobservice workers by state.",
+			[]string{"pool", "state"}, nil,
+		),
+		queueDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "jobservice_queue_depth"),
+			"Number of jobs waiting in the jobservice queue.",
+			[]string{"job_type"}, nil,
+		),
+		queueLatency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "jobservice_queue_latency_seconds"),
+			"Time the oldest job of the jobservice queue has been waiting.",
+			[]string{"job_type"}, nil,
+		),
+		queuePaused: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "jobservice_queue_paused"),
+			"Whether the jobservice queue is paused.",
+			[]string{"job_type"}, nil,
+		),
+	}, nil
+}
+
+func (c *jobserviceCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	queues, err := c.client.ListJobQueues(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving jobservice queues: %s", err)
+	}
+	for _, queue := range queues {
+		ch <- prometheus.MustNewConstMetric(
+			c.queueDepth, prometheus.GaugeValue, float64(queue.Count), queue.JobType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.queueLatency, prometheus.GaugeValue, float64(queue.Latency), queue.JobType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.queuePaused, prometheus.GaugeValue, boolToFloat(queue.Paused), queue.JobType,
+		)
+	}
+
+	pools, err := c.client.ListWorkerPools(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving jobservice pools: %s", err)
+	}
+	for _, pool := range pools {
+		ch <- prometheus.MustNewConstMetric(
+			c.concurrency, prometheus.GaugeValue, float64(pool.Concurrency), pool.WorkerPoolID, pool.Host,
+		)
+		workers, err := c.client.ListWorkers(ctx, pool.WorkerPoolID)
+		if err != nil {
+			return fmt.Errorf("error retrieving workers of pool %s: %s", pool.WorkerPoolID, err)
+		}
+		var busy, idle float64
+		for _, worker := range workers {
+			if worker.JobID != "" {
+				busy++
+			} else {
+				idle++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.workers, prometheus.GaugeValue, busy, pool.WorkerPoolID, "busy",
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.workers, prometheus.GaugeValue, idle, pool.WorkerPoolID, "idle",
+		)
+	}
+	return nil
+}
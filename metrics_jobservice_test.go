@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// jobserviceHarbor serves the given queues and one worker pool with a busy
+// and an idle worker.
+func jobserviceHarbor(queues string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jobservice/queues":
+			w.Write([]byte(queues))
+		case "/jobservice/pools":
+			w.Write([]byte(`[{"pid": 1, "worker_pool_id": "a1b2", "host": "jobservice-0", "concurrency": 10}]`))
+		case "/jobservice/pools/a1b2/workers":
+			w.Write([]byte(`[
+				{"id": "1", "pool_id": "a1b2", "job_name": "REPLICATION", "job_id": "j1"},
+				{"id": "2", "pool_id": "a1b2"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestJobservice(t *testing.T) {
+	hc, srv := testHarbor(jobserviceHarbor(`[
+		{"job_type": "REPLICATION", "count": 3, "latency": 120},
+		{"job_type": "GARBAGE_COLLECTION", "count": 0, "latency": 0, "paused": true}
+	]`))
+	defer srv.Close()
+	c, err := newJobserviceCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_jobservice_queue_depth", []string{"job_type", "REPLICATION"}, 3},
+		{"harbor_jobservice_queue_latency_seconds", []string{"job_type", "REPLICATION"}, 120},
+		{"harbor_jobservice_queue_paused", []string{"job_type", "REPLICATION"}, 0},
+		// An empty queue is still exported.
+		{"harbor_jobservice_queue_depth", []string{"job_type", "GARBAGE_COLLECTION"}, 0},
+		{"harbor_jobservice_queue_latency_seconds", []string{"job_type", "GARBAGE_COLLECTION"}, 0},
+		{"harbor_jobservice_queue_paused", []string{"job_type", "GARBAGE_COLLECTION"}, 1},
+		{"harbor_jobservice_pool_concurrency", []string{"pool", "a1b2", "host", "jobservice-0"}, 10},
+		{"harbor_jobservice_workers", []string{"pool", "a1b2", "state", "busy"}, 1},
+		{"harbor_jobservice_workers", []string{"pool", "a1b2", "state", "idle"}, 1},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+}
+
+// Without any queue the pools are still exported.
+func TestJobserviceNoQueues(t *testing.T) {
+	hc, srv := testHarbor(jobserviceHarbor(`[]`))
+	defer srv.Close()
+	c, err := newJobserviceCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(samples, "harbor_jobservice_queue_depth"); n != 0 {
+		t.Errorf("got %d queue series, want none", n)
+	}
+	if n := count(samples, "harbor_jobservice_workers"); n != 2 {
+		t.Errorf("got %d worker series, want 2", n)
+	}
+}
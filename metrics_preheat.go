@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("preheat", defaultEnabled, newPreheatCollector)
+}
+
+type preheatCollector struct {
+	client          HarborClient
+	filter          projectFilter
+	logger          log.Logger
+	providerUp      *prometheus.Desc
+	pingDuration    *prometheus.Desc
+	providerState   *prometheus.Desc
+	providerDefault *prometheus.Desc
+	policyEnabled   *prometheus.Desc
+	status          *prometheus.Desc
+	duration        *prometheus.Desc
+	tasks           *prometheus.Desc
+}
+
+func newPreheatCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapP2PPreheat); err != nil {
+		return nil, err
+	}
+	policyLabels := []string{"project", "policy"}
+	return &preheatCollector{
+		client: e.client,
+		filter: e.filter,
+		logger: e.logger,
+		providerUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_provider_up"),
+			"Whether Harbor could reach the enabled P2P provider instance.",
+			[]string{"provider", "vendor", "endpoint"}, nil,
+		),
+		pingDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_provider_ping_duration_seconds"),
+			"Time taken by Harbor to ping the P2P provider instance.",
+			[]string{"provider"}, nil,
+		),
+		providerState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_provider_enabled"),
+			"Whether the P2P provider instance is enabled.",
+			[]string{"provider"}, nil,
+		),
+		providerDefault: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_provider_default"),
+			"Whether the P2P provider instance is the default one.",
+			[]string{"provider"}, nil,
+		),
+		policyEnabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_policy_enabled"),
+			"Whether the preheat policy is enabled, with the provider it preheats on.",
+			[]string{"project", "policy", "provider"}, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_last_execution_status"),
+			"Status of the last execution of the preheat policy, 1 for the current status.",
+			append(policyLabels, "status"), nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_last_execution_duration_seconds"),
+			"Duration of the last execution of the preheat policy, once finished.",
+			policyLabels, nil,
+		),
+		tasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "p2p_preheat_last_execution_tasks"),
+			"Preheat tasks of the last execution of the preheat policy by result.",
+			append(policyLabels, "result"), nil,
+		),
+	}, nil
+}
+
+func (c *preheatCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	instances, err := c.client.ListPreheatInstances(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving preheat instances: %s", err)
+	}
+	for _, instance := range instances {
+		if err := c.updateProvider(ctx, ch, instance); err != nil {
+			return err
+		}
+	}
+
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		policies, err := c.client.ListPreheatPolicies(ctx, project.Name, nil)
+		if err != nil {
+			return fmt.Errorf("error retrieving preheat policies of %s: %s", project.Name, err)
+		}
+		for _, policy := range policies {
+			if err := c.updatePolicy(ctx, ch, project.Name, policy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *preheatCollector) updateProvider(ctx context.Context, ch chan<- prometheus.Metric, instance harbor.PreheatInstance) error {
+	ch <- prometheus.MustNewConstMetric(
+		c.providerState, prometheus.GaugeValue, boolToFloat(instance.Enabled), instance.Name,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.providerDefault, prometheus.GaugeValue, boolToFloat(instance.Default), instance.Name,
+	)
+	if !instance.Enabled {
+		return nil
+	}
+
+	up, took, err := ping(ctx, c.logger, "preheat instance "+instance.Name, func() error {
+		return c.client.PingPreheatInstance(ctx, instance.ID)
+	}, "provider", instance.Name, "endpoint", instance.Endpoint)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.pingDuration, prometheus.GaugeValue, took.Seconds(), instance.Name,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.providerUp, prometheus.GaugeValue, boolToFloat(up), instance.Name, instance.Vendor, instance.Endpoint,
+	)
+	return nil
+}
+
+func (c *preheatCollector) updatePolicy(ctx context.Context, ch chan<- prometheus.Metric, project string, policy harbor.PreheatPolicy) error {
+	ch <- prometheus.MustNewConstMetric(
+		c.policyEnabled, prometheus.GaugeValue, boolToFloat(policy.Enabled), project, policy.Name, policy.ProviderName,
+	)
+
+	// Not every release sorts as asked, so look for the latest execution
+	// among the first few.
+	executions, err := c.client.ListPreheatExecutions(ctx, project, policy.Name, &harbor.ListOptions{Sort: "-start_time", Limit: 10})
+	if err != nil {
+		return fmt.Errorf("error retrieving preheat executions of %s/%s: %s", project, policy.Name, err)
+	}
+	if len(executions) == 0 {
+		return nil
+	}
+	last := executions[0]
+	for _, execution := range executions[1:] {
+		if execution.StartTime.After(last.StartTime.Time) {
+			last = execution
+		}
+	}
+
+	status := jobStatus(last.Status)
+	for _, s := range jobStatuses {
+		var v float64
+		if s == status {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, v, project, policy.Name, s)
+	}
+	if jobFinished(status) && !last.EndTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.duration, prometheus.GaugeValue, last.EndTime.Sub(last.StartTime.Time).Seconds(), project, policy.Name,
+		)
+	}
+	if m := last.Metrics; m != nil {
+		for result, n := range map[string]int64{
+			"succeed":     m.SuccessTaskCount,
+			"failed":      m.ErrorTaskCount,
+			"in_progress": m.PendingTaskCount + m.RunningTaskCount + m.ScheduledTaskCount,
+			"stopped":     m.StoppedTaskCount,
+		} {
+			ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(n), project, policy.Name, result)
+		}
+	}
+	return nil
+}
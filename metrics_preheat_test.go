@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// preheatHarbor serves a healthy, an unreachable and a disabled P2P provider
+// and two preheat policies of the library project. Pinging forbidden
+// answers 403.
+func preheatHarbor(forbidden bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/p2p/preheat/instances":
+			w.Write([]byte(`[
+				{"id": 1, "name": "dragonfly", "vendor": "dragonfly", "endpoint": "http://dragonfly:8080", "enabled": true, "default": true},
+				{"id": 2, "name": "kraken", "vendor": "kraken", "endpoint": "http://kraken", "enabled": true},
+				{"id": 3, "name": "spare", "vendor": "dragonfly", "endpoint": "http://spare", "enabled": false}
+			]`))
+		case "/p2p/preheat/instances/ping":
+			var in struct {
+				ID int64 `json:"id"`
+			}
+			json.NewDecoder(r.Body).Decode(&in)
+			switch {
+			case forbidden:
+				http.Error(w, `{"errors": [{"code": "FORBIDDEN", "message": "forbidden"}]}`, http.StatusForbidden)
+			case in.ID == 1:
+				w.WriteHeader(http.StatusOK)
+			default:
+				http.Error(w, `{"errors": [{"code": "UNKNOWN", "message": "connection refused"}]}`, http.StatusInternalServerError)
+			}
+		case "/projects":
+			w.Write([]byte(`[{"project_id": 1, "name": "library"}]`))
+		case "/projects/library/preheat/policies":
+			w.Write([]byte(`[
+				{"id": 1, "name": "nightly", "provider_name": "dragonfly", "enabled": true},
+				{"id": 2, "name": "release", "provider_name": "kraken", "enabled": false}
+			]`))
+		case "/projects/library/preheat/policies/nightly/executions":
+			// Oldest first, as some releases answer regardless of the sort.
+			w.Write([]byte(`[
+				{"id": 8, "status": "Success", "start_time": "2026-10-16T02:00:00Z", "end_time": "2026-10-16T02:00:40Z"},
+				{"id": 9, "status": "Success", "start_time": "2026-10-17T02:00:00Z", "end_time": "2026-10-17T02:00:50Z"},
+				{
+					"id": 10, "status": "Error",
+					"start_time": "2026-10-18T02:00:00Z", "end_time": "2026-10-18T02:01:30Z",
+					"metrics": {"task_count": 7, "success_task_count": 4, "error_task_count": 2, "running_task_count": 1}
+				}
+			]`))
+		case "/projects/library/preheat/policies/release/executions":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestPreheat(t *testing.T) {
+	hc, srv := testHarbor(preheatHarbor(false))
+	defer srv.Close()
+	c, err := newPreheatCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_p2p_preheat_provider_up", []string{"provider", "dragonfly", "vendor", "dragonfly", "endpoint", "http://dragonfly:8080"}, 1},
+		{"harbor_p2p_preheat_provider_up", []string{"provider", "kraken"}, 0},
+		{"harbor_p2p_preheat_provider_enabled", []string{"provider", "dragonfly"}, 1},
+		{"harbor_p2p_preheat_provider_enabled", []string{"provider", "spare"}, 0},
+		{"harbor_p2p_preheat_provider_default", []string{"provider", "dragonfly"}, 1},
+		{"harbor_p2p_preheat_provider_default", []string{"provider", "kraken"}, 0},
+		{"harbor_p2p_preheat_provider_default", []string{"provider", "spare"}, 0},
+		{"harbor_p2p_preheat_policy_enabled", []string{"policy", "nightly", "provider", "dragonfly"}, 1},
+		{"harbor_p2p_preheat_policy_enabled", []string{"policy", "release", "provider", "kraken"}, 0},
+		{"harbor_p2p_preheat_last_execution_status", []string{"policy", "nightly", "status", "error"}, 1},
+		{"harbor_p2p_preheat_last_execution_status", []string{"policy", "nightly", "status", "success"}, 0},
+		{"harbor_p2p_preheat_last_execution_duration_seconds", []string{"policy", "nightly"}, 90},
+		{"harbor_p2p_preheat_last_execution_tasks", []string{"policy", "nightly", "result", "succeed"}, 4},
+		{"harbor_p2p_preheat_last_execution_tasks", []string{"policy", "nightly", "result", "failed"}, 2},
+		{"harbor_p2p_preheat_last_execution_tasks", []string{"policy", "nightly", "result", "in_progress"}, 1},
+		{"harbor_p2p_preheat_last_execution_tasks", []string{"policy", "nightly", "result", "stopped"}, 0},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+
+	// Disabled providers are not pinged.
+	if _, ok := find(samples, "harbor_p2p_preheat_provider_up", "provider", "spare"); ok {
+		t.Error("disabled provider was pinged")
+	}
+	if n := count(samples, "harbor_p2p_preheat_provider_ping_duration_seconds"); n != 2 {
+		t.Errorf("got %d ping durations, want 2", n)
+	}
+	// A policy that never ran only has its state.
+	if _, ok := find(samples, "harbor_p2p_preheat_last_execution_status", "policy", "release"); ok {
+		t.Error("status exported for a policy without executions")
+	}
+}
+
+// Lacking the permission to ping fails the collector instead of reporting
+// every provider down.
+func TestPreheatForbidden(t *testing.T) {
+	hc, srv := testHarbor(preheatHarbor(true))
+	defer srv.Close()
+	c, err := newPreheatCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err == nil || !strings.Contains(err.Error(), "dragonfly") {
+		t.Errorf("error = %v, want a failed ping of dragonfly", err)
+	}
+	if n := count(samples, "harbor_p2p_preheat_provider_up"); n != 0 {
+		t.Errorf("got %d provider_up series", n)
+	}
+}
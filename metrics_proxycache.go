@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// auditLogTimeFormat is how Harbor expects times in audit log queries.
+const auditLogTimeFormat = "2006-01-02 15:04:05"
+
+var proxyCacheWindow = kingpin.Flag("collector.proxycache.window", "Audit log window the proxy cache pulls, misses and estimated hit ratio are computed over.").Default("1h").Duration()
+
+func init() {
+	registerCollector("proxycache", defaultEnabled, newProxyCacheCollector)
+}
+
+type proxyCacheCollector struct {
+	client      HarborClient
+	filter      projectFilter
+	window      time.Duration
+	projectInfo *prometheus.Desc
+	artifacts   *prometheus.Desc
+	bytes       *prometheus.Desc
+	pulls       *prometheus.Desc
+	misses      *prometheus.Desc
+	hitRatio    *prometheus.Desc
+}
+
+func newProxyCacheCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapProxyCache); err != nil {
+		return nil, err
+	}
+	return &proxyCacheCollector{
+		client: e.client,
+		filter: e.filter,
+		window: e.settings["proxycache"].window,
+		projectInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_project_info"),
+			"Proxy cache project and the upstream registry it caches, always 1. The registry is the name of harbor_registry_endpoint_up.",
+			[]string{"project", "registry"}, nil,
+		),
+		artifacts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_artifacts"),
+			"Number of artifacts cached in the proxy cache project.",
+			[]string{"project"}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_bytes"),
+			"Storage used by the proxy cache project in bytes.",
+			[]string{"project"}, nil,
+		),
+		pulls: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_pulls"),
+			"Pulls from the proxy cache project within --collector.proxycache.window, from the audit log.",
+			[]string{"project"}, nil,
+		),
+		misses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_misses"),
+			"Artifacts fetched from upstream into the proxy cache project within --collector.proxycache.window, from the audit log.",
+			[]string{"project"}, nil,
+		),
+		hitRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "proxy_cache_hit_ratio"),
+			"Estimated share of pulls within --collector.proxycache.window served from the cache, from the audit log. Not exported without pulls.",
+			[]string{"project"}, nil,
+		),
+	}, nil
+}
+
+func (c *proxyCacheCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	var proxies []harbor.Project
+	for _, project := range projects {
+		if project.RegistryID <= 0 || !c.filter.match(project.Name) {
+			continue
+		}
+		proxies = append(proxies, project)
+	}
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	registries, err := c.client.ListRegistries(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving registries: %s", err)
+	}
+	// Whether the upstream is reachable is up to the registries collector.
+	names := make(map[int64]string)
+	for _, registry := range registries {
+		names[registry.ID] = registry.Name
+	}
+
+	quotas, err := c.client.ListQuotas(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving quotas: %s", err)
+	}
+	used := make(map[string]int64)
+	for _, quota := range quotas {
+		used[quota.Ref.Name] = quota.Used["storage"]
+	}
+
+	now := time.Now().UTC()
+	since := fmt.Sprintf("op_time=[%s~%s]", now.Add(-c.window).Format(auditLogTimeFormat), now.Format(auditLogTimeFormat))
+	for _, project := range proxies {
+		registry := names[project.RegistryID]
+		if registry == "" {
+			registry = strconv.FormatInt(project.RegistryID, 10)
+		}
+		ch <- prometheus.MustNewConstMetric(c.projectInfo, prometheus.GaugeValue, 1, project.Name, registry)
+		ch <- prometheus.MustNewConstMetric(
+			c.bytes, prometheus.GaugeValue, float64(used[project.Name]), project.Name,
+		)
+
+		repos, err := c.client.ListRepositories(ctx, project, nil)
+		if err != nil {
+			return fmt.Errorf("error retrieving repositories of %s: %s", project.Name, err)
+		}
+		var artifacts int64
+		for _, repo := range repos {
+			artifacts += repo.ArtifactCount
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.artifacts, prometheus.GaugeValue, float64(artifacts), project.Name,
+		)
+
+		if err := c.updateHits(ctx, ch, project.Name, since); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateHits estimates the cache hits from the audit log. Every pull is
+// logged, and an artifact fetched from upstream is logged as created in the
+// project, so pulls that created nothing were served from the cache.
+func (c *proxyCacheCollector) updateHits(ctx context.Context, ch chan<- prometheus.Metric, project, since string) error {
+	pulls, err := c.client.CountProjectLogs(ctx, project, &harbor.ListOptions{Query: "operation=pull," + since})
+	if err != nil {
+		return fmt.Errorf("error retrieving audit log of %s: %s", project, err)
+	}
+	misses, err := c.client.CountProjectLogs(ctx, project, &harbor.ListOptions{Query: "operation=create,resource_type=artifact," + since})
+	if err != nil {
+		return fmt.Errorf("error retrieving audit log of %s: %s", project, err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.pulls, prometheus.GaugeValue, float64(pulls), project)
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.GaugeValue, float64(misses), project)
+	if pulls == 0 {
+		return nil
+	}
+	hits := pulls - misses
+	if hits < 0 {
+		hits = 0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.hitRatio, prometheus.GaugeValue, float64(hits)/float64(pulls), project,
+	)
+	return nil
+}
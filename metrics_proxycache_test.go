@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestProxyCache(t *testing.T) {
+	var pings int32
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects":
+			w.Write([]byte(`[
+				{"project_id": 1, "name": "library"},
+				{"project_id": 2, "name": "dockerhub", "registry_id": 3},
+				{"project_id": 3, "name": "quay", "registry_id": 4}
+			]`))
+		case "/registries":
+			w.Write([]byte(`[{"id": 3, "name": "docker-hub", "type": "docker-hub", "url": "https://hub.docker.com"}]`))
+		case "/registries/ping":
+			atomic.AddInt32(&pings, 1)
+			w.WriteHeader(http.StatusOK)
+		case "/quotas":
+			w.Write([]byte(`[{"id": 2, "ref": {"id": 2, "name": "dockerhub"}, "hard": {"storage": -1}, "used": {"storage": 4096}}]`))
+		case "/projects/dockerhub/repositories":
+			w.Write([]byte(`[{"id": 1, "name": "dockerhub/library/nginx", "artifact_count": 3}, {"id": 2, "name": "dockerhub/library/redis", "artifact_count": 2}]`))
+		case "/projects/quay/repositories":
+			w.Write([]byte(`[]`))
+		case "/projects/dockerhub/logs", "/projects/quay/logs":
+			// Ten pulls of which four fetched from upstream in dockerhub,
+			// nothing pulled from quay.
+			total := "0"
+			if strings.HasPrefix(r.URL.Path, "/projects/dockerhub") {
+				total = "10"
+				if strings.Contains(r.URL.Query().Get("q"), "operation=create") {
+					total = "4"
+				}
+			}
+			w.Header().Set("X-Total-Count", total)
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+	c, err := newProxyCacheCollector(&Exporter{
+		client:   hc,
+		logger:   log.NewNopLogger(),
+		settings: map[string]collectorSettings{"proxycache": settingsFor("proxycache", &Config{})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_proxy_cache_project_info", []string{"project", "dockerhub", "registry", "docker-hub"}, 1},
+		// The registry of quay is gone, its ID stands in.
+		{"harbor_proxy_cache_project_info", []string{"project", "quay", "registry", "4"}, 1},
+		{"harbor_proxy_cache_artifacts", []string{"project", "dockerhub"}, 5},
+		{"harbor_proxy_cache_bytes", []string{"project", "dockerhub"}, 4096},
+		{"harbor_proxy_cache_pulls", []string{"project", "dockerhub"}, 10},
+		{"harbor_proxy_cache_misses", []string{"project", "dockerhub"}, 4},
+		{"harbor_proxy_cache_hit_ratio", []string{"project", "dockerhub"}, 0.6},
+		{"harbor_proxy_cache_pulls", []string{"project", "quay"}, 0},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	if _, ok := find(samples, "harbor_proxy_cache_hit_ratio", "project", "quay"); ok {
+		t.Error("hit ratio exported without pulls")
+	}
+	if _, ok := find(samples, "harbor_proxy_cache_project_info", "project", "library"); ok {
+		t.Error("regular project reported as proxy cache")
+	}
+	// Upstreams are pinged by the registries collector only.
+	if n := atomic.LoadInt32(&pings); n != 0 {
+		t.Errorf("pinged upstream registries %d times", n)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("quotas", defaultEnabled, newQuotasCollector)
+}
+
+type quotasCollector struct {
+	client       HarborClient
+	filter       projectFilter
+	hardBytes    *prometheus.Desc
+	usedBytes    *prometheus.Desc
+	usageRatio   *prometheus.Desc
+	hardCount    *prometheus.Desc
+	usedCount    *prometheus.Desc
+	repositories *prometheus.Desc
+}
+
+func newQuotasCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapQuotas); err != nil {
+		return nil, err
+	}
+	return &quotasCollector{
+		client: e.client,
+		filter: e.filter,
+		hardBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_quota_hard_bytes"),
+			"Storage quota of the project in bytes, -1 if unlimited.",
+			[]string{"project"}, nil,
+		),
+		usedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_quota_used_bytes"),
+			"Storage used by the project in bytes.",
+			[]string{"project"}, nil,
+		),
+		usageRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_quota_usage_ratio"),
+			"Used storage divided by the storage quota of the project, not exported for unlimited quotas.",
+			[]string{"project"}, nil,
+		),
+		hardCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_quota_hard_artifacts"),
+			"Artifact count quota of the project, -1 if unlimited. Harbor 1.9 and 1.10 only.",
+			[]string{"project"}, nil,
+		),
+		usedCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_quota_used_artifacts"),
+			"Artifacts counted against the quota of the project. Harbor 1.9 and 1.10 only.",
+			[]string{"project"}, nil,
+		),
+		repositories: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_repositories"),
+			"Number of repositories in the project.",
+			[]string{"project"}, nil,
+		),
+	}, nil
+}
+
+func (c *quotasCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	quotas, err := c.client.ListQuotas(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving quotas: %s", err)
+	}
+	withCount := c.client.Capabilities.Has(harbor.CapQuotaCount)
+	for _, quota := range quotas {
+		project := quota.Ref.Name
+		if project == "" || !c.filter.match(project) {
+			continue
+		}
+		hard, used := quota.Hard["storage"], quota.Used["storage"]
+		ch <- prometheus.MustNewConstMetric(
+			c.hardBytes, prometheus.GaugeValue, float64(hard), project,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.usedBytes, prometheus.GaugeValue, float64(used), project,
+		)
+		if hard > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.usageRatio, prometheus.GaugeValue, float64(used)/float64(hard), project,
+			)
+		}
+		if hardCount, ok := quota.Hard["count"]; ok && withCount {
+			ch <- prometheus.MustNewConstMetric(
+				c.hardCount, prometheus.GaugeValue, float64(hardCount), project,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.usedCount, prometheus.GaugeValue, float64(quota.Used["count"]), project,
+			)
+		}
+	}
+
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.repositories, prometheus.GaugeValue, float64(project.RepoCount), project.Name,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestQuotas(t *testing.T) {
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/quotas":
+			w.Write([]byte(`[
+				{"id": 1, "ref": {"id": 1, "name": "library"}, "hard": {"storage": -1}, "used": {"storage": 2048}},
+				{"id": 2, "ref": {"id": 2, "name": "team"}, "hard": {"storage": 1000}, "used": {"storage": 250}}
+			]`))
+		case "/projects":
+			w.Write([]byte(`[{"project_id": 1, "name": "library", "repo_count": 4}, {"project_id": 2, "name": "team", "repo_count": 0}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+	c, err := newQuotasCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_project_quota_hard_bytes", []string{"project", "library"}, -1},
+		{"harbor_project_quota_used_bytes", []string{"project", "library"}, 2048},
+		{"harbor_project_quota_hard_bytes", []string{"project", "team"}, 1000},
+		{"harbor_project_quota_usage_ratio", []string{"project", "team"}, 0.25},
+		{"harbor_project_repositories", []string{"project", "library"}, 4},
+		{"harbor_project_repositories", []string{"project", "team"}, 0},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	// An unlimited quota has no usage ratio, and 2.x has no count quota.
+	if _, ok := find(samples, "harbor_project_quota_usage_ratio", "project", "library"); ok {
+		t.Error("usage ratio exported for an unlimited quota")
+	}
+	if n := count(samples, "harbor_project_quota_hard_artifacts"); n != 0 {
+		t.Errorf("got %d count quota series on 2.7", n)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("registries", defaultEnabled, newRegistriesCollector)
+}
+
+type registriesCollector struct {
+	client       HarborClient
+	logger       log.Logger
+	up           *prometheus.Desc
+	pingDuration *prometheus.Desc
+	insecure     *prometheus.Desc
+	credential   *prometheus.Desc
+}
+
+func newRegistriesCollector(e *Exporter) (Collector, error) {
+	return &registriesCollector{
+		client: e.client,
+		logger: e.logger,
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "registry_endpoint_up"),
+			"Whether Harbor could reach and log in to the registry endpoint.",
+			[]string{"name", "type", "url"}, nil,
+		),
+		pingDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "registry_endpoint_ping_duration_seconds"),
+			"Time taken by Harbor to ping the registry endpoint.",
+			[]string{"name"}, nil,
+		),
+		insecure: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "registry_endpoint_insecure"),
+			"Whether certificate verification is disabled for the registry endpoint.",
+			[]string{"name"}, nil,
+		),
+		credential: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "registry_endpoint_credential_info"),
+			"How Harbor authenticates to the registry endpoint, always 1.",
+			[]string{"name", "credential_type"}, nil,
+		),
+	}, nil
+}
+
+func (c *registriesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	registries, err := c.client.ListRegistries(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving registries: %s", err)
+	}
+	for _, registry := range registries {
+		ch <- prometheus.MustNewConstMetric(
+			c.insecure, prometheus.GaugeValue, boolToFloat(registry.Insecure), registry.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.credential, prometheus.GaugeValue, 1, registry.Name, credentialType(registry),
+		)
+
+		up, took, err := ping(ctx, c.logger, "registry "+registry.Name, func() error {
+			return c.client.PingRegistry(ctx, registry.ID)
+		}, "registry", registry.Name, "url", registry.URL)
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.pingDuration, prometheus.GaugeValue, took.Seconds(), registry.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.up, prometheus.GaugeValue, boolToFloat(up), registry.Name, registry.Type, registry.URL,
+		)
+	}
+	return nil
+}
+
+// credentialType returns how Harbor authenticates to the registry, "none"
+// for anonymous access.
+func credentialType(registry harbor.Registry) string {
+	if registry.Credential == nil || registry.Credential.AccessKey == "" {
+		return "none"
+	}
+	return registry.Credential.Type
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// registriesHarbor serves a reachable registry with credentials and an
+// unreachable anonymous one. Pinging forbidden answers 403.
+func registriesHarbor(forbidden bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/registries":
+			w.Write([]byte(`[
+				{"id": 1, "name": "dr", "type": "harbor", "url": "https://harbor-dr.example.com",
+				 "credential": {"type": "basic", "access_key": "robot$dr"}},
+				{"id": 2, "name": "hub", "type": "docker-hub", "url": "https://hub.docker.com", "insecure": true}
+			]`))
+		case "/registries/ping":
+			var in struct {
+				ID int64 `json:"id"`
+			}
+			json.NewDecoder(r.Body).Decode(&in)
+			switch {
+			case forbidden:
+				http.Error(w, `{"errors": [{"code": "FORBIDDEN", "message": "forbidden"}]}`, http.StatusForbidden)
+			case in.ID == 1:
+				w.WriteHeader(http.StatusOK)
+			default:
+				http.Error(w, `{"errors": [{"code": "BAD_REQUEST", "message": "failed to ping registry"}]}`, http.StatusBadRequest)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestRegistries(t *testing.T) {
+	hc, srv := testHarbor(registriesHarbor(false))
+	defer srv.Close()
+	c, err := newRegistriesCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatalf("an unreachable registry failed the collector: %s", err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_registry_endpoint_up", []string{"name", "dr", "type", "harbor", "url", "https://harbor-dr.example.com"}, 1},
+		{"harbor_registry_endpoint_up", []string{"name", "hub", "type", "docker-hub"}, 0},
+		{"harbor_registry_endpoint_insecure", []string{"name", "dr"}, 0},
+		{"harbor_registry_endpoint_insecure", []string{"name", "hub"}, 1},
+		{"harbor_registry_endpoint_credential_info", []string{"name", "dr", "credential_type", "basic"}, 1},
+		{"harbor_registry_endpoint_credential_info", []string{"name", "hub", "credential_type", "none"}, 1},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	if n := count(samples, "harbor_registry_endpoint_ping_duration_seconds"); n != 2 {
+		t.Errorf("got %d ping durations, want 2", n)
+	}
+}
+
+// Lacking the permission to ping fails the collector instead of reporting
+// every registry down.
+func TestRegistriesForbidden(t *testing.T) {
+	hc, srv := testHarbor(registriesHarbor(true))
+	defer srv.Close()
+	c, err := newRegistriesCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err == nil || !strings.Contains(err.Error(), "dr") {
+		t.Errorf("error = %v, want a failed ping of dr", err)
+	}
+	if n := count(samples, "harbor_registry_endpoint_up"); n != 0 {
+		t.Errorf("got %d endpoint_up series", n)
+	}
+}
@@ -1,70 +1,239 @@
 package main
 
 import (
-	"encoding/json"
-	"strconv"
+	"context"
+	"fmt"
 
-	"github.com/go-kit/kit/log/level"
+	"github.com/c4po/harbor_exporter/harbor"
 	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-func (e *Exporter) collectReplicationsMetric(ch chan<- prometheus.Metric) bool {
-	type policiesMetrics []struct {
-		Id   float64
-		Name string
-		// Extra fields omitted for maintainability: not relevant for current metrics
-	}
-	type policyMetric []struct {
-		Status      string
-		Failed      float64
-		Succeed     float64
-		In_progress float64
-		Stopped     float64
-		// Extra fields omitted for maintainability: not relevant for current metrics
+var replicationsHistory = kingpin.Flag("collector.replications.history", "Number of recent executions per replication policy counted by status.").Default("10").Int()
+
+// replicationTriggers are the ways a replication execution is started.
+var replicationTriggers = []string{"manual", "scheduled", "event_based"}
+
+func init() {
+	registerCollector("replications", defaultEnabled, newReplicationsCollector)
+}
+
+type replicationsCollector struct {
+	client            HarborClient
+	history           int
+	replicationStatus *prometheus.Desc
+	replicationTasks  *prometheus.Desc
+	enabled           *prometheus.Desc
+	recent            *prometheus.Desc
+	trigger           *prometheus.Desc
+	startTime         *prometheus.Desc
+	endTime           *prometheus.Desc
+	duration          *prometheus.Desc
+	failedTasks       *prometheus.Desc
+	lastSuccess       *prometheus.Desc
+}
+
+func newReplicationsCollector(e *Exporter) (Collector, error) {
+	labels := []string{"repl_pol_name"}
+	return &replicationsCollector{
+		client:  e.client,
+		history: e.settings["replications"].history,
+		replicationStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_status"),
+			"Get status of the last execution of this replication policy: Succeed = 1, any other status = 0.",
+			labels, nil,
+		),
+		replicationTasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_tasks"),
+			"Get number of replication tasks, with various results, in the latest execution of this replication policy.",
+			[]string{"repl_pol_name", "result"}, nil,
+		),
+		enabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_policy_enabled"),
+			"Whether the replication policy is enabled, with its source and destination registry.",
+			[]string{"repl_pol_name", "source", "destination"}, nil,
+		),
+		recent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_recent_executions"),
+			"Recent executions of the replication policy by status, see --collector.replications.history.",
+			[]string{"repl_pol_name", "status"}, nil,
+		),
+		trigger: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_execution_trigger"),
+			"How the last execution of the replication policy was started, 1 for the current trigger.",
+			[]string{"repl_pol_name", "trigger"}, nil,
+		),
+		startTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_execution_start_timestamp_seconds"),
+			"Start time of the last execution of the replication policy.",
+			labels, nil,
+		),
+		endTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_execution_end_timestamp_seconds"),
+			"End time of the last execution of the replication policy, once finished.",
+			labels, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_execution_duration_seconds"),
+			"Duration of the last execution of the replication policy, once finished.",
+			labels, nil,
+		),
+		failedTasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_execution_failed_tasks"),
+			"Failed tasks of the last execution of the replication policy by resource type, only exported for failures.",
+			[]string{"repl_pol_name", "resource_type"}, nil,
+		),
+		lastSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "replication_last_success_timestamp_seconds"),
+			"End time of the last successful execution of the replication policy.",
+			labels, nil,
+		),
+	}, nil
+}
+
+func (c *replicationsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	policies, err := c.client.ListReplicationPolicies(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving replication policies: %s", err)
 	}
 
-	policiesBody := e.client.request("/replication/policies")
-	var policiesData policiesMetrics
+	for _, policy := range policies {
+		ch <- prometheus.MustNewConstMetric(
+			c.enabled, prometheus.GaugeValue, boolToFloat(policy.Enabled),
+			policy.Name, registryName(policy.SrcRegistry), registryName(policy.DestRegistry),
+		)
 
-	if err := json.Unmarshal(policiesBody, &policiesData); err != nil {
-		level.Error(e.logger).Log("msg", "Error retrieving replication policies", "err", err.Error())
-		return false
+		history := c.history
+		if history < 1 {
+			history = 1
+		}
+		data, err := c.client.ListReplicationExecutions(ctx, policy.ID, &harbor.ListOptions{Limit: history})
+		if err != nil {
+			return fmt.Errorf("error retrieving replication data for policy %d: %s", policy.ID, err)
+		}
+		if len(data) > history {
+			data = data[:history]
+		}
+		if err := c.updateHistory(ctx, ch, policy, data); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		execution := data[0]
+		var replStatus float64
+		replStatus = 0
+		if execution.Status == "Succeed" {
+			replStatus = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.replicationStatus, prometheus.GaugeValue, replStatus, policy.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.replicationTasks, prometheus.GaugeValue, float64(execution.Failed), policy.Name, "failed",
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.replicationTasks, prometheus.GaugeValue, float64(execution.Succeed), policy.Name, "succeed",
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.replicationTasks, prometheus.GaugeValue, float64(execution.InProgress), policy.Name, "in_progress",
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.replicationTasks, prometheus.GaugeValue, float64(execution.Stopped), policy.Name, "stopped",
+		)
 	}
+	return nil
+}
 
-	for i := range policiesData {
-		policyId := strconv.FormatFloat(policiesData[i].Id, 'f', 0, 32)
-		policyName := policiesData[i].Name
+// updateHistory exports the recent executions of a policy, newest first, and
+// its last success.
+func (c *replicationsCollector) updateHistory(ctx context.Context, ch chan<- prometheus.Metric, policy harbor.ReplicationPolicy, executions []harbor.ReplicationExecution) error {
+	recent := make(map[string]float64)
+	for _, s := range jobStatuses {
+		recent[s] = 0
+	}
+	var lastSuccess *harbor.ReplicationExecution
+	for i, execution := range executions {
+		status := jobStatus(execution.Status)
+		recent[status]++
+		if status == "success" && lastSuccess == nil {
+			lastSuccess = &executions[i]
+		}
+	}
+	for status, n := range recent {
+		ch <- prometheus.MustNewConstMetric(c.recent, prometheus.GaugeValue, n, policy.Name, status)
+	}
 
-		body := e.client.request("/replication/executions?policy_id=" + policyId + "&page=1&page_size=1")
-		var data policyMetric
+	// The last success may be older than the recent executions.
+	if lastSuccess == nil && len(executions) > 0 {
+		found, err := c.client.ListReplicationExecutionsByStatus(ctx, policy.ID, "Succeed", &harbor.ListOptions{Limit: 1})
+		if err != nil {
+			return fmt.Errorf("error retrieving successful replications for policy %d: %s", policy.ID, err)
+		}
+		if len(found) > 0 {
+			lastSuccess = &found[0]
+		}
+	}
+	if lastSuccess != nil && !lastSuccess.EndTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.lastSuccess, prometheus.GaugeValue, unixSeconds(lastSuccess.EndTime), policy.Name,
+		)
+	}
 
-		if err := json.Unmarshal(body, &data); err != nil {
-			level.Error(e.logger).Log("msg", "Error retrieving replication data for policy "+policyId, "err", err.Error())
-			return false
+	if len(executions) == 0 {
+		return nil
+	}
+	last := executions[0]
+	trigger := last.Trigger
+	found := false
+	for _, t := range replicationTriggers {
+		var v float64
+		if t == trigger {
+			v, found = 1, true
 		}
+		ch <- prometheus.MustNewConstMetric(c.trigger, prometheus.GaugeValue, v, policy.Name, t)
+	}
+	if !found && trigger != "" {
+		ch <- prometheus.MustNewConstMetric(c.trigger, prometheus.GaugeValue, 1, policy.Name, trigger)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.startTime, prometheus.GaugeValue, unixSeconds(last.StartTime), policy.Name,
+	)
+	if !jobFinished(jobStatus(last.Status)) {
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.endTime, prometheus.GaugeValue, unixSeconds(last.EndTime), policy.Name,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.duration, prometheus.GaugeValue, last.EndTime.Sub(last.StartTime.Time).Seconds(), policy.Name,
+	)
 
-		for i := range data {
-			var replStatus float64
-			replStatus = 0
-			if data[i].Status == "Succeed" {
-				replStatus = 1
-			}
-			ch <- prometheus.MustNewConstMetric(
-				replicationStatus, prometheus.GaugeValue, replStatus, policyName,
-			)
-			ch <- prometheus.MustNewConstMetric(
-				replicationTasks, prometheus.GaugeValue, data[i].Failed, policyName, "failed",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				replicationTasks, prometheus.GaugeValue, data[i].Succeed, policyName, "succeed",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				replicationTasks, prometheus.GaugeValue, data[i].In_progress, policyName, "in_progress",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				replicationTasks, prometheus.GaugeValue, data[i].Stopped, policyName, "stopped",
-			)
+	if last.Failed == 0 {
+		return nil
+	}
+	tasks, err := c.client.ListReplicationTasks(ctx, last.ID, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving replication tasks of execution %d: %s", last.ID, err)
+	}
+	failed := make(map[string]float64)
+	for _, task := range tasks {
+		if jobStatus(task.Status) == "error" {
+			failed[task.ResourceType]++
 		}
 	}
-	return true
+	for resourceType, n := range failed {
+		ch <- prometheus.MustNewConstMetric(c.failedTasks, prometheus.GaugeValue, n, policy.Name, resourceType)
+	}
+	return nil
+}
+
+// registryName names the source or destination of a replication policy,
+// "local" for the Harbor instance itself.
+func registryName(registry *harbor.Registry) string {
+	if registry == nil || registry.ID == 0 {
+		return "local"
+	}
+	return registry.Name
 }
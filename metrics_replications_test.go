@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// A policy whose executions all failed has no last success, and one that
+// never ran only reports whether it is enabled.
+func TestReplicationsNoSuccess(t *testing.T) {
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/replication/policies":
+			w.Write([]byte(`[
+				{"id": 1, "name": "nightly", "enabled": true, "src_registry": {"id": 0}, "dest_registry": {"id": 2, "name": "dr"}},
+				{"id": 2, "name": "fresh", "enabled": false, "src_registry": {"id": 3, "name": "hub"}}
+			]`))
+		case "/replication/executions":
+			q := r.URL.Query()
+			if q.Get("policy_id") != "1" || q.Get("status") == "Succeed" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[
+				{"id": 12, "policy_id": 1, "status": "Failed", "trigger": "scheduled", "total": 3, "failed": 1, "succeed": 2,
+				 "start_time": "2026-10-17T02:00:00Z", "end_time": "2026-10-17T02:05:00Z"},
+				{"id": 11, "policy_id": 1, "status": "Failed", "trigger": "manual", "total": 3, "failed": 3,
+				 "start_time": "2026-10-16T02:00:00Z", "end_time": "2026-10-16T02:01:00Z"}
+			]`))
+		case "/replication/executions/12/tasks":
+			w.Write([]byte(`[
+				{"id": 1, "execution_id": 12, "status": "Failed", "resource_type": "image"},
+				{"id": 2, "execution_id": 12, "status": "Succeed", "resource_type": "image"},
+				{"id": 3, "execution_id": 12, "status": "Succeed", "resource_type": "chart"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+	c, err := newReplicationsCollector(&Exporter{
+		client:   hc,
+		logger:   log.NewNopLogger(),
+		settings: map[string]collectorSettings{"replications": settingsFor("replications", &Config{})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_replication_policy_enabled", []string{"repl_pol_name", "nightly", "source", "local", "destination", "dr"}, 1},
+		{"harbor_replication_policy_enabled", []string{"repl_pol_name", "fresh", "source", "hub", "destination", "local"}, 0},
+		{"harbor_replication_status", []string{"repl_pol_name", "nightly"}, 0},
+		{"harbor_replication_recent_executions", []string{"repl_pol_name", "nightly", "status", "error"}, 2},
+		{"harbor_replication_recent_executions", []string{"repl_pol_name", "nightly", "status", "success"}, 0},
+		{"harbor_replication_last_execution_trigger", []string{"repl_pol_name", "nightly", "trigger", "scheduled"}, 1},
+		{"harbor_replication_last_execution_trigger", []string{"repl_pol_name", "nightly", "trigger", "manual"}, 0},
+		{"harbor_replication_last_execution_duration_seconds", []string{"repl_pol_name", "nightly"}, 300},
+		{"harbor_replication_last_execution_failed_tasks", []string{"repl_pol_name", "nightly", "resource_type", "image"}, 1},
+		{"harbor_replication_recent_executions", []string{"repl_pol_name", "fresh", "status", "success"}, 0},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	if n := count(samples, "harbor_replication_last_success_timestamp_seconds"); n != 0 {
+		t.Errorf("got %d last success series without a successful execution", n)
+	}
+	if _, ok := find(samples, "harbor_replication_status", "repl_pol_name", "fresh"); ok {
+		t.Error("status exported for a policy that never ran")
+	}
+	if _, ok := find(samples, "harbor_replication_last_execution_failed_tasks", "resource_type", "chart"); ok {
+		t.Error("failed tasks exported for a resource type without failures")
+	}
+}
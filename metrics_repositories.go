@@ -1,68 +1,91 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"strings"
 
+	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
-	queryAllRepo = `
-SELECT
-    r.repository_id as repo_id,
-    r.name as repo_name,
-    r.pull_count as pull_count,
-    count(al.log_id) as push_count
-FROM
-    repository as r
-    JOIN access_log as al ON al.repo_name = r.name
-WHERE
-    al.operation = 'push'
-GROUP By
-    r.repository_id;`
-	queryRepoTag = `SELECT
-	count(a.id) as tag_count
-FROM
-	artifact as a
-	JOIN repository as r ON a.repo = r.name
-WHERE
-	r.repository_id = $1;`
-	queryImagePull = `
-SELECT
-	a.repo as repo_name,
-	a.tag as tag_name,
-	count(al.log_id) as pull_count
-FROM
-	access_log as al
-	JOIN artifact as a ON a.repo = al.repo_name
-	AND a.tag = repo_tag
-WHERE
-	al.operation = 'pull'
-GROUP BY
-	a.repo,
-	a.tag;`
-	queryProjectSize = `
-SELECT
-    p.name as project_name,
-    sum(b.size) as size
-FROM
-    blob AS b
-    JOIN artifact_blob AS ab ON ab.digest_blob = b.digest
-    JOIN artifact AS a ON a.digest = digest_af
-    JOIN repository AS r ON a.repo = r.name
-    JOIN project AS p ON r.project_id = p.project_id
-GROUP BY
-    p.name;`
-)
+func init() {
+	registerCollector("repositories", defaultEnabled, newRepositoriesCollector)
+}
+
+type repositoriesCollector struct {
+	pg                    Postgres
+	filter                projectFilter
+	logger                log.Logger
+	repositoriesPullCount *prometheus.Desc
+	repositoriesPushCount *prometheus.Desc
+	repositoriesTagsCount *prometheus.Desc
+	imagePullCount        *prometheus.Desc
+	projectSize           *prometheus.Desc
+	schemaVersion         *prometheus.Desc
+}
+
+func newRepositoriesCollector(e *Exporter) (Collector, error) {
+	if e.pg.connStr == "" {
+		return nil, &unavailableError{"no database configured"}
+	}
+	return &repositoriesCollector{
+		pg:     e.pg,
+		filter: e.filter,
+		logger: e.logger,
+		repositoriesPullCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "repositories_pull_total"),
+			"Get public repositories which are accessed most.).",
+			[]string{"repo_name", "repo_id"}, nil,
+		),
+		repositoriesPushCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "repositories_push_total"),
+			"Get public repositories which are accessed most.).",
+			[]string{"repo_name", "repo_id"}, nil,
+		),
+		repositoriesTagsCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "repositories_tags_total"),
+			"Get public repositories which are accessed most.).",
+			[]string{"repo_name", "repo_id"}, nil,
+		),
+		imagePullCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "image_pull_count"),
+			"Get public image which are accessed most.).",
+			[]string{"repo_name", "repo_tag"}, nil,
+		),
+		projectSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_size"),
+			"Get Project all image size sum).",
+			[]string{"project_name"}, nil,
+		),
+		schemaVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "db_schema_version"),
+			"Version of the harbor database schema from schema_migrations.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func (c *repositoriesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	db := c.pg.db
 
-func (e *Exporter) collectRepositoriesMetric(ch chan<- prometheus.Metric, version string) bool {
-	db, err := sql.Open("postgres", e.pg.connStr)
+	version, dirty, err := readSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.schemaVersion, prometheus.GaugeValue, float64(version),
+	)
+	queries, err := queriesFor(version, dirty)
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error connect to db", "err", err)
+		return err
 	}
-	defer db.Close()
+	level.Debug(c.logger).Log("msg", "Using query set", "schema_version", version, "harbor", queries.release)
+
+	// A failing query only skips its own metrics, the others are still
+	// exported and the last error is returned once everything has been tried.
+	var lastErr error
 
 	// 得到全部repo的信息
 	type Repo struct {
@@ -73,29 +96,37 @@ func (e *Exporter) collectRepositoriesMetric(ch chan<- prometheus.Metric, versio
 		tag_count  float64
 	}
 
-	res, err := db.Query(queryAllRepo)
+	res, err := db.QueryContext(ctx, queries.allRepo)
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error get repo info", "err", err)
-	}
-	repo := &Repo{}
-	for res.Next() {
-		err := res.Scan(&repo.repo_id, &repo.repo_name, &repo.pull_count, &repo.push_count)
-		if err != nil {
-			level.Error(e.client.logger).Log("msg", "Error get repo info", "err", err)
-		}
-
-		res := db.QueryRow(queryRepoTag, repo.repo_id)
-		res.Scan(&repo.tag_count)
+		level.Error(c.logger).Log("msg", "Error get repo info", "err", err)
+		lastErr = err
+	} else {
+		repo := &Repo{}
+		for res.Next() {
+			err := res.Scan(&repo.repo_id, &repo.repo_name, &repo.pull_count, &repo.push_count, &repo.tag_count)
+			if err != nil {
+				level.Error(c.logger).Log("msg", "Error get repo info", "err", err)
+				lastErr = err
+				continue
+			}
+			if !c.filter.match(projectOf(repo.repo_name)) {
+				continue
+			}
 
-		ch <- prometheus.MustNewConstMetric(
-			repositoriesPullCount, prometheus.GaugeValue, repo.pull_count, repo.repo_name, repo.repo_id,
-		)
-		ch <- prometheus.MustNewConstMetric(
-			repositoriesPushCount, prometheus.GaugeValue, repo.push_count, repo.repo_name, repo.repo_id,
-		)
-		ch <- prometheus.MustNewConstMetric(
-			repositoriesTagsCount, prometheus.GaugeValue, repo.tag_count, repo.repo_name, repo.repo_id,
-		)
+			ch <- prometheus.MustNewConstMetric(
+				c.repositoriesPullCount, prometheus.GaugeValue, repo.pull_count, repo.repo_name, repo.repo_id,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.repositoriesPushCount, prometheus.GaugeValue, repo.push_count, repo.repo_name, repo.repo_id,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.repositoriesTagsCount, prometheus.GaugeValue, repo.tag_count, repo.repo_name, repo.repo_id,
+			)
+		}
+		if err := res.Err(); err != nil {
+			lastErr = err
+		}
+		res.Close()
 	}
 
 	// 得到全部image的信息
@@ -104,16 +135,29 @@ func (e *Exporter) collectRepositoriesMetric(ch chan<- prometheus.Metric, versio
 		tag_name   string
 		pull_count float64
 	}
-	res, err = db.Query(queryImagePull)
+	res, err = db.QueryContext(ctx, queries.imagePull)
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error get image data", "err", err)
-	}
-	image := &Image{}
-	for res.Next() {
-		res.Scan(&image.repo_name, &image.tag_name, &image.pull_count)
-		ch <- prometheus.MustNewConstMetric(
-			imagePullCount, prometheus.GaugeValue, image.pull_count, image.repo_name, image.tag_name,
-		)
+		level.Error(c.logger).Log("msg", "Error get image data", "err", err)
+		lastErr = err
+	} else {
+		image := &Image{}
+		for res.Next() {
+			if err := res.Scan(&image.repo_name, &image.tag_name, &image.pull_count); err != nil {
+				level.Error(c.logger).Log("msg", "Error get image data", "err", err)
+				lastErr = err
+				continue
+			}
+			if !c.filter.match(projectOf(image.repo_name)) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.imagePullCount, prometheus.GaugeValue, image.pull_count, image.repo_name, image.tag_name,
+			)
+		}
+		if err := res.Err(); err != nil {
+			lastErr = err
+		}
+		res.Close()
 	}
 
 	// 得到全部项目的占用空间
@@ -121,18 +165,36 @@ func (e *Exporter) collectRepositoriesMetric(ch chan<- prometheus.Metric, versio
 		project_name string
 		size         float64
 	}
-	res, err = db.Query(queryProjectSize)
+	res, err = db.QueryContext(ctx, queries.projectSize)
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error get project size", "err", err)
-	}
-	project := &Project{}
-	var mb float64 = 1048576
-	for res.Next() {
-		res.Scan(&project.project_name, &project.size)
-		ch <- prometheus.MustNewConstMetric(
-			projectSize, prometheus.GaugeValue, project.size/mb, project.project_name,
-		)
+		level.Error(c.logger).Log("msg", "Error get project size", "err", err)
+		lastErr = err
+	} else {
+		project := &Project{}
+		var mb float64 = 1048576
+		for res.Next() {
+			if err := res.Scan(&project.project_name, &project.size); err != nil {
+				level.Error(c.logger).Log("msg", "Error get project size", "err", err)
+				lastErr = err
+				continue
+			}
+			if !c.filter.match(project.project_name) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.projectSize, prometheus.GaugeValue, project.size/mb, project.project_name,
+			)
+		}
+		if err := res.Err(); err != nil {
+			lastErr = err
+		}
+		res.Close()
 	}
 
-	return true
+	return lastErr
+}
+
+// projectOf returns the project part of a repository name.
+func projectOf(repoName string) string {
+	return strings.SplitN(repoName, "/", 2)[0]
 }
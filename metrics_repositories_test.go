@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// repoTables answers the repositories queries of a 2.x schema for two
+// repositories of the library project and one of the tmp project.
+func repoTables() map[string]testTable {
+	tables := schemaTable(100, false)
+	tables["tag_count"] = testTable{
+		columns: []string{"repo_id", "repo_name", "pull_count", "push_count", "tag_count"},
+		rows: [][]driver.Value{
+			{"1", "library/nginx", int64(12), int64(3), int64(2)},
+			{"2", "library/redis", int64(0), int64(1), int64(0)},
+			{"3", "tmp/scratch", int64(5), int64(5), int64(5)},
+		},
+	}
+	tables["tag_name"] = testTable{
+		columns: []string{"repo_name", "tag_name", "pull_count"},
+		rows: [][]driver.Value{
+			{"library/nginx", "latest", int64(10)},
+			{"library/nginx", "1.25", int64(2)},
+			{"tmp/scratch", "latest", int64(5)},
+		},
+	}
+	tables["sum(b.size)"] = testTable{
+		columns: []string{"project_name", "size"},
+		rows: [][]driver.Value{
+			{"library", int64(3 << 20)},
+			{"tmp", int64(1 << 20)},
+		},
+	}
+	return tables
+}
+
+// The collector must not query while a result is still open, so a pool of
+// one connection is enough.
+func TestRepositoriesSingleConnection(t *testing.T) {
+	db := testDB(t, repoTables())
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	conf := &Config{Filters: FiltersConfig{Projects: ProjectsFilter{Exclude: "tmp"}}}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := newRepositoriesCollector(&Exporter{
+		pg:     Postgres{connStr: "test", db: db},
+		filter: conf.projectFilter,
+		logger: log.NewNopLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		samples []sample
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		samples, err := collect(t, c)
+		done <- result{samples, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector blocked on the connection pool")
+	}
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_db_schema_version", nil, 100},
+		{"harbor_repositories_pull_total", []string{"repo_name", "library/nginx", "repo_id", "1"}, 12},
+		{"harbor_repositories_push_total", []string{"repo_name", "library/nginx"}, 3},
+		{"harbor_repositories_tags_total", []string{"repo_name", "library/nginx"}, 2},
+		{"harbor_repositories_tags_total", []string{"repo_name", "library/redis"}, 0},
+		{"harbor_image_pull_count", []string{"repo_name", "library/nginx", "repo_tag", "latest"}, 10},
+		{"harbor_project_size", []string{"project_name", "library"}, 3},
+	} {
+		v, ok := find(res.samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	// Filtered projects are left out.
+	for _, name := range []string{"harbor_repositories_tags_total", "harbor_image_pull_count"} {
+		if _, ok := find(res.samples, name, "repo_name", "tmp/scratch"); ok {
+			t.Errorf("%s exported for an excluded project", name)
+		}
+	}
+}
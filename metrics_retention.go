@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// retentionHistory is how many executions of a policy are searched for the
+// last successful one.
+const retentionHistory = 20
+
+func init() {
+	registerCollector("retention", defaultEnabled, newRetentionCollector)
+}
+
+type retentionCollector struct {
+	client      HarborClient
+	filter      projectFilter
+	logger      log.Logger
+	hasPolicy   *prometheus.Desc
+	status      *prometheus.Desc
+	duration    *prometheus.Desc
+	dryRun      *prometheus.Desc
+	artifacts   *prometheus.Desc
+	lastSuccess *prometheus.Desc
+}
+
+func newRetentionCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapRetention); err != nil {
+		return nil, err
+	}
+	return &retentionCollector{
+		client: e.client,
+		filter: e.filter,
+		logger: e.logger,
+		hasPolicy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_policy_configured"),
+			"Whether the project has a tag retention policy.",
+			[]string{"project"}, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_last_execution_status"),
+			"Status of the last retention execution of the project, 1 for the current status.",
+			[]string{"project", "status"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_last_execution_duration_seconds"),
+			"Duration of the last retention execution of the project, once finished.",
+			[]string{"project"}, nil,
+		),
+		dryRun: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_last_execution_dry_run"),
+			"Whether the last retention execution of the project was a dry run.",
+			[]string{"project"}, nil,
+		),
+		artifacts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_last_execution_artifacts"),
+			"Artifacts retained and deleted by the last retention execution of the project.",
+			[]string{"project", "result"}, nil,
+		),
+		lastSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "retention_last_success_timestamp_seconds"),
+			"End time of the last successful retention execution of the project.",
+			[]string{"project"}, nil,
+		),
+	}, nil
+}
+
+func (c *retentionCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		retentionID, err := strconv.ParseInt(project.Metadata["retention_id"], 10, 64)
+		if err != nil || retentionID <= 0 {
+			ch <- prometheus.MustNewConstMetric(c.hasPolicy, prometheus.GaugeValue, 0, project.Name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.hasPolicy, prometheus.GaugeValue, 1, project.Name)
+		if err := c.updateProject(ctx, ch, project.Name, retentionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *retentionCollector) updateProject(ctx context.Context, ch chan<- prometheus.Metric, project string, retentionID int64) error {
+	executions, err := c.client.ListRetentionExecutions(ctx, retentionID, &harbor.ListOptions{Limit: retentionHistory})
+	if harbor.IsNotFound(err) {
+		// The project points at a policy that was deleted.
+		level.Debug(c.logger).Log("msg", "Retention policy not found", "project", project, "retention_id", retentionID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error retrieving retention executions of %s: %s", project, err)
+	}
+	if len(executions) == 0 {
+		return nil
+	}
+
+	last := executions[0]
+	var lastSuccess *harbor.RetentionExecution
+	for i, execution := range executions {
+		if execution.StartTime.After(last.StartTime.Time) {
+			last = execution
+		}
+		if jobStatus(execution.Status) == "success" && !execution.DryRun &&
+			(lastSuccess == nil || execution.EndTime.After(lastSuccess.EndTime.Time)) {
+			lastSuccess = &executions[i]
+		}
+	}
+
+	status := jobStatus(last.Status)
+	for _, s := range jobStatuses {
+		var v float64
+		if s == status {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, v, project, s)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.dryRun, prometheus.GaugeValue, boolToFloat(last.DryRun), project,
+	)
+	if lastSuccess != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.lastSuccess, prometheus.GaugeValue, unixSeconds(lastSuccess.EndTime), project,
+		)
+	}
+	if !jobFinished(status) {
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.duration, prometheus.GaugeValue, last.EndTime.Sub(last.StartTime.Time).Seconds(), project,
+	)
+
+	tasks, err := c.client.ListRetentionTasks(ctx, retentionID, last.ID, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving retention tasks of %s: %s", project, err)
+	}
+	var retained, deleted int64
+	for _, task := range tasks {
+		retained += task.Retained
+		deleted += task.Total - task.Retained
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.artifacts, prometheus.GaugeValue, float64(retained), project, "retained",
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.artifacts, prometheus.GaugeValue, float64(deleted), project, "deleted",
+	)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRetention(t *testing.T) {
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects":
+			w.Write([]byte(`[
+				{"project_id": 1, "name": "library", "metadata": {"retention_id": "5"}},
+				{"project_id": 2, "name": "team", "metadata": {}},
+				{"project_id": 3, "name": "stale", "metadata": {"retention_id": "9"}}
+			]`))
+		case "/retentions/5/executions":
+			// The last run failed after a dry run and an older success.
+			w.Write([]byte(`[
+				{"id": 12, "policy_id": 5, "status": "Failed", "start_time": "2026-10-18T01:00:00Z", "end_time": "2026-10-18T01:02:00Z"},
+				{"id": 11, "policy_id": 5, "status": "Succeed", "dry_run": true, "start_time": "2026-10-17T12:00:00Z", "end_time": "2026-10-17T12:01:00Z"},
+				{"id": 10, "policy_id": 5, "status": "Succeed", "start_time": "2026-10-17T01:00:00Z", "end_time": "2026-10-17T01:05:00Z"}
+			]`))
+		case "/retentions/5/executions/12/tasks":
+			w.Write([]byte(`[
+				{"id": 1, "execution_id": 12, "repository": "nginx", "status": "Error", "total": 10, "retained": 7},
+				{"id": 2, "execution_id": 12, "repository": "redis", "status": "Success", "total": 3, "retained": 3}
+			]`))
+		default:
+			// The policy of stale was deleted.
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+	c, err := newRetentionCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastSuccess := float64(time.Date(2026, 10, 17, 1, 5, 0, 0, time.UTC).Unix())
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_retention_policy_configured", []string{"project", "library"}, 1},
+		{"harbor_retention_policy_configured", []string{"project", "team"}, 0},
+		{"harbor_retention_policy_configured", []string{"project", "stale"}, 1},
+		{"harbor_retention_last_execution_status", []string{"project", "library", "status", "error"}, 1},
+		{"harbor_retention_last_execution_status", []string{"project", "library", "status", "success"}, 0},
+		{"harbor_retention_last_execution_dry_run", []string{"project", "library"}, 0},
+		{"harbor_retention_last_execution_duration_seconds", []string{"project", "library"}, 120},
+		{"harbor_retention_last_execution_artifacts", []string{"project", "library", "result", "retained"}, 10},
+		{"harbor_retention_last_execution_artifacts", []string{"project", "library", "result", "deleted"}, 3},
+		// Dry runs do not count as a success.
+		{"harbor_retention_last_success_timestamp_seconds", []string{"project", "library"}, lastSuccess},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	if _, ok := find(samples, "harbor_retention_last_execution_status", "project", "stale"); ok {
+		t.Error("status exported for a deleted policy")
+	}
+}
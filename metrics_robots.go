@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var robotsExpiryWindow = kingpin.Flag("collector.robots.expiry-window", "How far ahead enabled robot accounts are counted as expiring.").Default("168h").Duration()
+
+func init() {
+	registerCollector("robots", defaultEnabled, newRobotsCollector)
+}
+
+type robotsCollector struct {
+	client      HarborClient
+	filter      projectFilter
+	window      time.Duration
+	expiry      *prometheus.Desc
+	disabled    *prometheus.Desc
+	permissions *prometheus.Desc
+	expiring    *prometheus.Desc
+	expired     *prometheus.Desc
+}
+
+func newRobotsCollector(e *Exporter) (Collector, error) {
+	labels := []string{"robot", "project", "level"}
+	return &robotsCollector{
+		client: e.client,
+		filter: e.filter,
+		window: e.settings["robots"].expiryWindow,
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "robot_expiry_timestamp_seconds"),
+			"Expiry time of the robot account, not exported for robots that never expire.",
+			labels, nil,
+		),
+		disabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "robot_disabled"),
+			"Whether the robot account is disabled.",
+			labels, nil,
+		),
+		permissions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "robot_permissions"),
+			"Number of actions the robot account is allowed to perform.",
+			labels, nil,
+		),
+		expiring: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "robots_expiring"),
+			"Number of enabled robot accounts expiring within --collector.robots.expiry-window.",
+			[]string{"project"}, nil,
+		),
+		expired: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "robots_expired"),
+			"Number of enabled robot accounts that have expired.",
+			[]string{"project"}, nil,
+		),
+	}, nil
+}
+
+// projectRobot is a robot with the project it belongs to, empty for system
+// robots spanning projects.
+type projectRobot struct {
+	harbor.Robot
+	project string
+}
+
+func (c *robotsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	robots, err := c.listRobots(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiring := make(map[string]float64)
+	expired := make(map[string]float64)
+	for _, robot := range robots {
+		if robot.project != "" && !c.filter.match(robot.project) {
+			continue
+		}
+		level := robot.Level
+		if level == "" {
+			level = "project"
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.disabled, prometheus.GaugeValue, boolToFloat(robot.IsDisabled()), robot.Name, robot.project, level,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.permissions, prometheus.GaugeValue, float64(robot.PermissionCount()), robot.Name, robot.project, level,
+		)
+
+		// Every project with robots gets a summary, zero or not.
+		expiring[robot.project] += 0
+		expired[robot.project] += 0
+		if robot.ExpiresAt <= 0 {
+			continue
+		}
+		expiresAt := time.Unix(robot.ExpiresAt, 0)
+		ch <- prometheus.MustNewConstMetric(
+			c.expiry, prometheus.GaugeValue, float64(robot.ExpiresAt), robot.Name, robot.project, level,
+		)
+		if robot.IsDisabled() {
+			continue
+		}
+		switch {
+		case expiresAt.Before(now):
+			expired[robot.project]++
+		case expiresAt.Before(now.Add(c.window)):
+			expiring[robot.project]++
+		}
+	}
+
+	for project, n := range expiring {
+		ch <- prometheus.MustNewConstMetric(c.expiring, prometheus.GaugeValue, n, project)
+	}
+	for project, n := range expired {
+		ch <- prometheus.MustNewConstMetric(c.expired, prometheus.GaugeValue, n, project)
+	}
+	return nil
+}
+
+// listRobots returns all robots, from /robots where available and project by
+// project on older releases.
+func (c *robotsCollector) listRobots(ctx context.Context) ([]projectRobot, error) {
+	var robots []projectRobot
+	all, err := c.client.ListRobots(ctx, nil)
+	if err == nil {
+		for _, robot := range all {
+			robots = append(robots, projectRobot{robot, robotProject(robot)})
+		}
+		return robots, nil
+	}
+	if !harbor.IsUnsupported(err) && !harbor.IsNotFound(err) {
+		return nil, fmt.Errorf("error retrieving robots: %s", err)
+	}
+
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		list, err := c.client.ListProjectRobots(ctx, project.ProjectID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving robots of %s: %s", project.Name, err)
+		}
+		for _, robot := range list {
+			robots = append(robots, projectRobot{robot, project.Name})
+		}
+	}
+	return robots, nil
+}
+
+// robotProject returns the project a robot of the /robots API is limited to,
+// or nothing if it covers several or all projects.
+func robotProject(robot harbor.Robot) string {
+	var project string
+	for _, p := range robot.Permissions {
+		if p.Kind != "project" {
+			continue
+		}
+		if p.Namespace == "*" || (project != "" && project != p.Namespace) {
+			return ""
+		}
+		project = p.Namespace
+	}
+	return project
+}
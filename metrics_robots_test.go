@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRobots(t *testing.T) {
+	now := time.Now()
+	day := int64(24 * time.Hour / time.Second)
+	push := `[{"resource": "repository", "action": "push"}, {"resource": "repository", "action": "pull"}]`
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots" {
+			http.NotFound(w, r)
+			return
+		}
+		robot := func(id int, name, level, namespace string, expiresAt int64, disabled bool) string {
+			return fmt.Sprintf(`{"id": %d, "name": %q, "level": %q, "disable": %t, "expires_at": %d,
+				"permissions": [{"kind": "project", "namespace": %q, "access": %s}]}`,
+				id, name, level, disabled, expiresAt, namespace, push)
+		}
+		fmt.Fprintf(w, "[%s, %s, %s, %s, %s]",
+			robot(1, "robot$library+ci", "project", "library", -1, false),
+			robot(2, "robot$library+deploy", "project", "library", now.Unix()+day, false),
+			robot(3, "robot$library+old", "project", "library", now.Unix()-day, false),
+			robot(4, "robot$library+off", "project", "library", now.Unix()-day, true),
+			robot(5, "robot$mirror", "system", "*", now.Unix()+30*day, false),
+		)
+	})
+	defer srv.Close()
+	c, err := newRobotsCollector(&Exporter{
+		client:   hc,
+		logger:   log.NewNopLogger(),
+		settings: map[string]collectorSettings{"robots": settingsFor("robots", &Config{})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_robot_disabled", []string{"robot", "robot$library+ci", "project", "library", "level", "project"}, 0},
+		{"harbor_robot_disabled", []string{"robot", "robot$library+off"}, 1},
+		{"harbor_robot_permissions", []string{"robot", "robot$library+ci"}, 2},
+		{"harbor_robot_expiry_timestamp_seconds", []string{"robot", "robot$library+deploy"}, float64(now.Unix() + day)},
+		{"harbor_robot_disabled", []string{"robot", "robot$mirror", "project", "", "level", "system"}, 0},
+		// Disabled robots do not count.
+		{"harbor_robots_expiring", []string{"project", "library"}, 1},
+		{"harbor_robots_expired", []string{"project", "library"}, 1},
+		{"harbor_robots_expiring", []string{"project", ""}, 0},
+		{"harbor_robots_expired", []string{"project", ""}, 0},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	// A robot that never expires has no expiry.
+	if _, ok := find(samples, "harbor_robot_expiry_timestamp_seconds", "robot", "robot$library+ci"); ok {
+		t.Error("expiry exported for a robot that never expires")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// vulnDBUpdatedProperty is the adapter property holding the last update of
+// the vulnerability database, RFC 3339 formatted.
+const vulnDBUpdatedProperty = "harbor.scanner-adapter/vulnerability-database-updated-at"
+
+func init() {
+	registerCollector("scanners", defaultEnabled, newScannersCollector)
+}
+
+type scannersCollector struct {
+	client        HarborClient
+	logger        log.Logger
+	scannerInfo   *prometheus.Desc
+	isDefault     *prometheus.Desc
+	disabled      *prometheus.Desc
+	up            *prometheus.Desc
+	probeDuration *prometheus.Desc
+	vulnDBUpdated *prometheus.Desc
+}
+
+func newScannersCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapScanners); err != nil {
+		return nil, err
+	}
+	return &scannersCollector{
+		client: e.client,
+		logger: e.logger,
+		scannerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_info"),
+			"Registered scanner adapter, always 1.",
+			[]string{"scanner", "adapter", "vendor", "version", "url"}, nil,
+		),
+		isDefault: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_default"),
+			"Whether the scanner is the system default.",
+			[]string{"scanner"}, nil,
+		),
+		disabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_disabled"),
+			"Whether the scanner is disabled.",
+			[]string{"scanner"}, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_up"),
+			"Whether Harbor could fetch the metadata of the scanner adapter.",
+			[]string{"scanner"}, nil,
+		),
+		probeDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_probe_duration_seconds"),
+			"Time taken to fetch the metadata of the scanner adapter.",
+			[]string{"scanner"}, nil,
+		),
+		vulnDBUpdated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "scanner_vulnerability_database_updated_timestamp_seconds"),
+			"Last update of the vulnerability database of the scanner, as reported by the adapter.",
+			[]string{"scanner"}, nil,
+		),
+	}, nil
+}
+
+func (c *scannersCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	scanners, err := c.client.ListScanners(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving scanners: %s", err)
+	}
+	for _, scanner := range scanners {
+		ch <- prometheus.MustNewConstMetric(
+			c.scannerInfo, prometheus.GaugeValue, 1, scanner.Name, scanner.Adapter, scanner.Vendor, scanner.Version, scanner.URL,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.isDefault, prometheus.GaugeValue, boolToFloat(scanner.IsDefault), scanner.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.disabled, prometheus.GaugeValue, boolToFloat(scanner.Disabled), scanner.Name,
+		)
+
+		start := time.Now()
+		md, err := c.client.GetScannerMetadata(ctx, scanner.UUID)
+		ch <- prometheus.MustNewConstMetric(
+			c.probeDuration, prometheus.GaugeValue, time.Since(start).Seconds(), scanner.Name,
+		)
+		if err != nil {
+			// Running out of time is not the scanner's fault.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			level.Warn(c.logger).Log("msg", "Scanner adapter not reachable", "scanner", scanner.Name, "err", err)
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, scanner.Name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, scanner.Name)
+
+		if updated, ok := md.Properties[vulnDBUpdatedProperty]; ok && updated != "" {
+			t, err := time.Parse(time.RFC3339Nano, updated)
+			if err != nil {
+				level.Warn(c.logger).Log("msg", "Unable to parse vulnerability database update time", "scanner", scanner.Name, "value", updated, "err", err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.vulnDBUpdated, prometheus.GaugeValue, float64(t.UnixNano())/1e9, scanner.Name,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestScanners(t *testing.T) {
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scanners":
+			w.Write([]byte(`[
+				{"uuid": "trivy-uuid", "name": "Trivy", "adapter": "Trivy", "vendor": "Aqua Security", "version": "v0.50.0", "url": "http://trivy:8080", "is_default": true},
+				{"uuid": "clair-uuid", "name": "Clair", "adapter": "Clair", "vendor": "CoreOS", "version": "2.x", "url": "http://clair:8080", "disabled": true}
+			]`))
+		case "/scanners/trivy-uuid/metadata":
+			w.Write([]byte(`{
+				"scanner": {"name": "Trivy", "vendor": "Aqua Security", "version": "v0.50.0"},
+				"properties": {"harbor.scanner-adapter/vulnerability-database-updated-at": "2026-10-17T06:00:00Z"}
+			}`))
+		case "/scanners/clair-uuid/metadata":
+			// Harbor relays the failure of an unhealthy adapter.
+			http.Error(w, `{"errors": [{"code": "UNKNOWN", "message": "adapter unreachable"}]}`, http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+	c, err := newScannersCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatalf("an unhealthy adapter failed the collector: %s", err)
+	}
+
+	updated := float64(time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC).Unix())
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_scanner_info", []string{"scanner", "Trivy", "adapter", "Trivy", "url", "http://trivy:8080"}, 1},
+		{"harbor_scanner_default", []string{"scanner", "Trivy"}, 1},
+		{"harbor_scanner_default", []string{"scanner", "Clair"}, 0},
+		{"harbor_scanner_disabled", []string{"scanner", "Clair"}, 1},
+		{"harbor_scanner_up", []string{"scanner", "Trivy"}, 1},
+		{"harbor_scanner_up", []string{"scanner", "Clair"}, 0},
+		{"harbor_scanner_vulnerability_database_updated_timestamp_seconds", []string{"scanner", "Trivy"}, updated},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+	if n := count(samples, "harbor_scanner_probe_duration_seconds"); n != 2 {
+		t.Errorf("got %d probe durations, want 2", n)
+	}
+	if _, ok := find(samples, "harbor_scanner_vulnerability_database_updated_timestamp_seconds", "scanner", "Clair"); ok {
+		t.Error("database update exported for an unreachable adapter")
+	}
+}
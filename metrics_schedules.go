@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var schedulesExpected = kingpin.Flag("collector.schedules.expected", "Vendor type that should have a schedule, counted as 0 when there is none. Repeatable.").Default("GARBAGE_COLLECTION", "SCAN_ALL").Strings()
+
+func init() {
+	registerCollector("schedules", defaultEnabled, newSchedulesCollector)
+}
+
+type schedulesCollector struct {
+	client   HarborClient
+	logger   log.Logger
+	expected []string
+	info     *prometheus.Desc
+	nextRun  *prometheus.Desc
+	count    *prometheus.Desc
+	paused   *prometheus.Desc
+}
+
+func newSchedulesCollector(e *Exporter) (Collector, error) {
+	if err := e.client.Require(harbor.CapSchedules); err != nil {
+		return nil, err
+	}
+	// Harbor does not stop a vendor from having several schedules, so the
+	// schedule ID tells them apart.
+	labels := []string{"vendor_type", "vendor_id", "schedule_id"}
+	return &schedulesCollector{
+		client:   e.client,
+		logger:   e.logger,
+		expected: e.settings["schedules"].expected,
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "schedule_info"),
+			"Schedule of a periodic job with its cron expression, always 1.",
+			append(labels, "cron"), nil,
+		),
+		nextRun: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "schedule_next_run_timestamp_seconds"),
+			"Next run of the schedule according to its cron expression, evaluated in UTC.",
+			labels, nil,
+		),
+		count: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "schedules"),
+			"Number of schedules of the vendor type, 0 for expected types without one.",
+			[]string{"vendor_type"}, nil,
+		),
+		paused: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "schedule_paused"),
+			"Whether the scheduler is paused. Harbor pauses all schedules at once.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func (c *schedulesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	schedules, err := c.client.ListSchedules(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving schedules: %s", err)
+	}
+	// Harbor only reports the state of "all" job types.
+	paused, err := c.client.GetSchedulePaused(ctx, "all")
+	if err != nil {
+		return fmt.Errorf("error retrieving scheduler status: %s", err)
+	}
+
+	counts := make(map[string]float64)
+	for _, vendorType := range c.expected {
+		counts[vendorType] = 0
+	}
+	now := time.Now().UTC()
+	for _, schedule := range schedules {
+		counts[schedule.VendorType]++
+		vendorID := strconv.FormatInt(schedule.VendorID, 10)
+		id := strconv.FormatInt(schedule.ID, 10)
+		ch <- prometheus.MustNewConstMetric(
+			c.info, prometheus.GaugeValue, 1, schedule.VendorType, vendorID, id, schedule.Cron,
+		)
+
+		cron, err := parseCron(schedule.Cron)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "Unable to parse schedule", "vendor_type", schedule.VendorType, "vendor_id", vendorID, "schedule_id", id, "err", err)
+			continue
+		}
+		if next := cron.next(now); !next.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.nextRun, prometheus.GaugeValue, float64(next.Unix()), schedule.VendorType, vendorID, id,
+			)
+		}
+	}
+
+	for vendorType, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.count, prometheus.GaugeValue, n, vendorType)
+	}
+	ch <- prometheus.MustNewConstMetric(c.paused, prometheus.GaugeValue, boolToFloat(paused))
+	return nil
+}
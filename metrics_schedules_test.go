@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestSchedules(t *testing.T) {
+	hc, srv := testHarbor(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schedules":
+			// Two schedules of the same retention policy.
+			w.Write([]byte(`[
+				{"id": 1, "vendor_type": "GARBAGE_COLLECTION", "vendor_id": -1, "cron": "0 0 0 * * 6"},
+				{"id": 7, "vendor_type": "RETENTION", "vendor_id": 3, "cron": "0 0 1 * * *"},
+				{"id": 8, "vendor_type": "RETENTION", "vendor_id": 3, "cron": "not a cron"}
+			]`))
+		case "/schedules/all/paused":
+			w.Write([]byte(`{"paused": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer srv.Close()
+
+	settings := settingsFor("schedules", &Config{})
+	settings.expected = []string{"GARBAGE_COLLECTION", "SCAN_ALL"}
+	c, err := newSchedulesCollector(&Exporter{
+		client:   hc,
+		logger:   log.NewNopLogger(),
+		settings: map[string]collectorSettings{"schedules": settings},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count(samples, "harbor_schedule_info"); n != 3 {
+		t.Errorf("got %d schedule_info series, want 3", n)
+	}
+	for _, id := range []string{"7", "8"} {
+		if _, ok := find(samples, "harbor_schedule_info", "vendor_type", "RETENTION", "vendor_id", "3", "schedule_id", id); !ok {
+			t.Errorf("schedule %s missing", id)
+		}
+	}
+	// Unparsable schedules have no next run.
+	if n := count(samples, "harbor_schedule_next_run_timestamp_seconds"); n != 2 {
+		t.Errorf("got %d next run series, want 2", n)
+	}
+	for vendorType, want := range map[string]float64{"GARBAGE_COLLECTION": 1, "RETENTION": 2, "SCAN_ALL": 0} {
+		if v, ok := find(samples, "harbor_schedules", "vendor_type", vendorType); !ok || v != want {
+			t.Errorf("harbor_schedules{vendor_type=%q} = %v (found %v), want %v", vendorType, v, ok, want)
+		}
+	}
+	if n := count(samples, "harbor_schedule_paused"); n != 1 {
+		t.Errorf("got %d schedule_paused series, want 1", n)
+	}
+	if v, _ := find(samples, "harbor_schedule_paused"); v != 1 {
+		t.Errorf("harbor_schedule_paused = %v, want 1", v)
+	}
+}
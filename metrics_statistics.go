@@ -1,54 +1,66 @@
 package main
 
 import (
-	"encoding/json"
-	"github.com/go-kit/kit/log/level"
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func (e *Exporter) collectStatisticsMetric(ch chan<- prometheus.Metric) bool {
-
-	type statisticsMetric struct {
-		Total_project_count   float64
-		Public_project_count  float64
-		Private_project_count float64
-		Public_repo_count     float64
-		Total_repo_count      float64
-		Private_repo_count    float64
-	}
+func init() {
+	registerCollector("statistics", defaultEnabled, newStatisticsCollector)
+}
 
-	body := e.client.request("/statistics")
+type statisticsCollector struct {
+	client       HarborClient
+	projectCount *prometheus.Desc
+	repoCount    *prometheus.Desc
+}
 
-	var data statisticsMetric
+func newStatisticsCollector(e *Exporter) (Collector, error) {
+	return &statisticsCollector{
+		client: e.client,
+		projectCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "project_count_total"),
+			"projects number relevant to the user",
+			[]string{"type"}, nil,
+		),
+		repoCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "repo_count_total"),
+			"repositories number relevant to the user",
+			[]string{"type"}, nil,
+		),
+	}, nil
+}
 
-	if err := json.Unmarshal(body, &data); err != nil {
-		level.Error(e.logger).Log(err.Error())
-		return false
+func (c *statisticsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	data, err := c.client.GetStatistics(ctx)
+	if err != nil {
+		return err
 	}
 
 	ch <- prometheus.MustNewConstMetric(
-		projectCount, prometheus.GaugeValue, data.Total_project_count, "total_project",
+		c.projectCount, prometheus.GaugeValue, float64(data.TotalProjectCount), "total_project",
 	)
 
 	ch <- prometheus.MustNewConstMetric(
-		projectCount, prometheus.GaugeValue, data.Public_project_count, "public_project",
+		c.projectCount, prometheus.GaugeValue, float64(data.PublicProjectCount), "public_project",
 	)
 
 	ch <- prometheus.MustNewConstMetric(
-		projectCount, prometheus.GaugeValue, data.Private_project_count, "private_project",
+		c.projectCount, prometheus.GaugeValue, float64(data.PrivateProjectCount), "private_project",
 	)
 
 	ch <- prometheus.MustNewConstMetric(
-		repoCount, prometheus.GaugeValue, data.Public_repo_count, "public_repo",
+		c.repoCount, prometheus.GaugeValue, float64(data.PublicRepoCount), "public_repo",
 	)
 
 	ch <- prometheus.MustNewConstMetric(
-		repoCount, prometheus.GaugeValue, data.Total_repo_count, "total_repo",
+		c.repoCount, prometheus.GaugeValue, float64(data.TotalRepoCount), "total_repo",
 	)
 
 	ch <- prometheus.MustNewConstMetric(
-		repoCount, prometheus.GaugeValue, data.Private_repo_count, "private_repo",
+		c.repoCount, prometheus.GaugeValue, float64(data.PrivateRepoCount), "private_repo",
 	)
 
-	return true
+	return nil
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("systeminfo", defaultEnabled, newSystemInfoCollector)
+}
+
+type systemInfoCollector struct {
+	client       HarborClient
+	logger       log.Logger
+	info         *prometheus.Desc
+	storageBytes *prometheus.Desc
+}
+
+func newSystemInfoCollector(e *Exporter) (Collector, error) {
+	return &systemInfoCollector{
+		client: e.client,
+		logger: e.logger,
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "info"),
+			"Harbor version and configuration from /systeminfo, always 1.",
+			[]string{"version", "auth_mode", "registry_url", "external_url", "project_creation_restriction", "self_registration", "read_only", "storage_provider"}, nil,
+		),
+		storageBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "registry_storage_bytes"),
+			"Size of the registry storage from /systeminfo/volumes.",
+			[]string{"storage"}, nil,
+		),
+	}, nil
+}
+
+func (c *systemInfoCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	info, err := c.client.GetSystemInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving systeminfo: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.info, prometheus.GaugeValue, 1,
+		info.HarborVersion,
+		info.AuthMode,
+		info.RegistryURL,
+		info.ExternalURL,
+		info.ProjectCreationRestriction,
+		strconv.FormatBool(info.SelfRegistration),
+		strconv.FormatBool(info.ReadOnly),
+		info.RegistryStorageProviderName,
+	)
+
+	// Only shown to admins and only for some storage drivers and releases.
+	volumes, err := c.client.GetSystemVolumes(ctx)
+	if harbor.IsNotFound(err) || harbor.IsForbidden(err) {
+		level.Debug(c.logger).Log("msg", "Registry storage size not available", "err", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error retrieving systeminfo volumes: %s", err)
+	}
+	var total, free float64
+	for _, volume := range volumes {
+		total += float64(volume.Total)
+		free += float64(volume.Free)
+	}
+	if len(volumes) > 0 {
+		ch <- prometheus.MustNewConstMetric(c.storageBytes, prometheus.GaugeValue, total, "total")
+		ch <- prometheus.MustNewConstMetric(c.storageBytes, prometheus.GaugeValue, free, "free")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// systemInfoHarbor serves /systeminfo, and its volumes to admins only.
+func systemInfoHarbor(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/systeminfo":
+			version := ""
+			if admin {
+				version = `"harbor_version": "v2.7.1-6d8bd7a1",`
+			}
+			w.Write([]byte(`{` + version + `
+				"auth_mode": "oidc_auth", "registry_url": "harbor.example.com",
+				"external_url": "https://harbor.example.com", "project_creation_restriction": "adminonly",
+				"self_registration": false, "read_only": true, "registry_storage_provider_name": "filesystem"
+			}`))
+		case "/systeminfo/volumes":
+			if !admin {
+				http.Error(w, `{"errors": [{"code": "FORBIDDEN", "message": "forbidden"}]}`, http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"storage": [{"total": 1000, "free": 400}, {"total": 500, "free": 100}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestSystemInfo(t *testing.T) {
+	hc, srv := testHarbor(systemInfoHarbor(true))
+	defer srv.Close()
+	c, err := newSystemInfoCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		name   string
+		labels []string
+		value  float64
+	}{
+		{"harbor_info", []string{
+			"version", "v2.7.1-6d8bd7a1", "auth_mode", "oidc_auth", "registry_url", "harbor.example.com",
+			"external_url", "https://harbor.example.com", "project_creation_restriction", "adminonly",
+			"self_registration", "false", "read_only", "true", "storage_provider", "filesystem",
+		}, 1},
+		{"harbor_registry_storage_bytes", []string{"storage", "total"}, 1500},
+		{"harbor_registry_storage_bytes", []string{"storage", "free"}, 500},
+	} {
+		v, ok := find(samples, want.name, want.labels...)
+		if !ok || v != want.value {
+			t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+		}
+	}
+}
+
+// Without admin rights the version is hidden and the storage size not
+// shown, which is not a failure.
+func TestSystemInfoNotAdmin(t *testing.T) {
+	hc, srv := testHarbor(systemInfoHarbor(false))
+	defer srv.Close()
+	c, err := newSystemInfoCollector(&Exporter{client: hc, logger: log.NewNopLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := collect(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := find(samples, "harbor_info", "version", "", "read_only", "true"); !ok {
+		t.Error("harbor_info missing")
+	}
+	if n := count(samples, "harbor_registry_storage_bytes"); n != 0 {
+		t.Errorf("got %d storage series without admin rights", n)
+	}
+}
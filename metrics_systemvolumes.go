@@ -2,10 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"strconv"
 	"strings"
 
-	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,17 +14,78 @@ import (
 	"k8s.io/client-go/tools/remotecommand"
 )
 
-func (e *Exporter) collectSystemVolumesMetric(ch chan<- prometheus.Metric) bool {
-	type systemVolumesMetric struct {
-		Storage struct {
-			Total float64
-			Free  float64
-		}
+func init() {
+	registerCollector("systemvolumes", defaultEnabled, newSystemVolumesCollector)
+}
+
+type systemVolumesCollector struct {
+	kubeClient KubeClient
+	storage    string
+	// local is set when the storage is mounted into the exporter, otherwise
+	// df is run in the registry pod.
+	local         bool
+	systemVolumes *prometheus.Desc
+}
+
+func newSystemVolumesCollector(e *Exporter) (Collector, error) {
+	local := e.opts.mode != modeKubernetes
+	if e.opts.storage == "" {
+		return nil, &unavailableError{"registry storage location unknown"}
 	}
-	var data systemVolumesMetric
-	pods, err := e.kubeClient.client.CoreV1().Pods(e.kubeClient.namespace).List(metav1.ListOptions{LabelSelector: "component=registry"})
+	if !local && e.kubeClient.client == nil {
+		return nil, &unavailableError{"no kubernetes client available"}
+	}
+	return &systemVolumesCollector{
+		kubeClient: e.kubeClient,
+		storage:    e.opts.storage,
+		local:      local,
+		systemVolumes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "system_volumes_bytes"),
+			"Get system volume info (total/free size).",
+			[]string{"storage"}, nil,
+		),
+	}, nil
+}
+
+func (c *systemVolumesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var (
+		total, free uint64
+		err         error
+	)
+	if c.local {
+		total, free, err = statfs(c.storage)
+	} else {
+		total, free, err = c.podDF(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Reported in GiB despite the metric name, as it always has been.
+	var gb float64 = 1 << 30
+	ch <- prometheus.MustNewConstMetric(
+		c.systemVolumes, prometheus.GaugeValue, float64(total)/gb, "total",
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.systemVolumes, prometheus.GaugeValue, float64(free)/gb, "free",
+	)
+
+	return nil
+}
+
+// podDF runs df in the registry pod and returns the total and available
+// bytes of the storage.
+func (c *systemVolumesCollector) podDF(ctx context.Context) (total, free uint64, err error) {
+	var pods v1.PodList
+	err = c.kubeClient.client.CoreV1().RESTClient().Get().
+		Namespace(c.kubeClient.namespace).
+		Resource("pods").
+		VersionedParams(&metav1.ListOptions{LabelSelector: "component=registry"}, scheme.ParameterCodec).
+		Context(ctx).
+		Do().
+		Into(&pods)
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error getting registry pod", "err", err)
+		return 0, 0, fmt.Errorf("error getting registry pod: %s", err)
 	}
 	var targetPod v1.Pod
 	for _, pod := range pods.Items {
@@ -32,9 +94,12 @@ func (e *Exporter) collectSystemVolumesMetric(ch chan<- prometheus.Metric) bool
 		}
 		targetPod = pod
 	}
+	if targetPod.Name == "" {
+		return 0, 0, fmt.Errorf("no registry pod found in namespace %s", c.kubeClient.namespace)
+	}
 
-	req := e.kubeClient.client.CoreV1().RESTClient().Post().Resource("pods").
-		Namespace(e.kubeClient.namespace).
+	req := c.kubeClient.client.CoreV1().RESTClient().Post().Resource("pods").
+		Namespace(c.kubeClient.namespace).
 		Name(targetPod.Name).
 		SubResource("exec").
 		VersionedParams(
@@ -43,51 +108,52 @@ func (e *Exporter) collectSystemVolumesMetric(ch chan<- prometheus.Metric) bool
 				Command: []string{
 					"sh",
 					"-c",
-					"df " + e.opts.storage,
+					"df -P " + c.storage,
 				},
 				Stdin:  false,
 				Stdout: true,
 				Stderr: false,
 				TTY:    false,
 			}, scheme.ParameterCodec)
-	exec, err := remotecommand.NewSPDYExecutor(e.kubeClient.config, "POST", req.URL())
+	exec, err := remotecommand.NewSPDYExecutor(c.kubeClient.config, "POST", req.URL())
 	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error building remote exec request", "err", err)
+		return 0, 0, fmt.Errorf("error building remote exec request: %s", err)
 	}
+	// The exec stream can not be cancelled, so wait for it in the background
+	// and give up on it once the deadline passes.
 	var stdout bytes.Buffer
-	exec.Stream(remotecommand.StreamOptions{
-		Stdin:  nil,
-		Stdout: &stdout,
-		Stderr: nil,
-		Tty:    false,
-	})
-
-	result := strings.Split(strings.TrimSpace(strings.Split(stdout.String(), "\n")[1]), " ")
-	var values []string
-	for _, val := range result {
-		if val != "" {
-			values = append(values, val)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- exec.Stream(remotecommand.StreamOptions{
+			Stdin:  nil,
+			Stdout: &stdout,
+			Stderr: nil,
+			Tty:    false,
+		})
+	}()
+	select {
+	case err := <-streamErr:
+		if err != nil {
+			return 0, 0, fmt.Errorf("error running df in pod %s: %s", targetPod.Name, err)
 		}
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
 	}
-	var mb float64 = 1048576
-	data.Storage.Free, err = strconv.ParseFloat(values[3], 64)
-	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error format free", "err", err)
-	}
-	data.Storage.Free = data.Storage.Free / mb
 
-	data.Storage.Total, err = strconv.ParseFloat(values[1], 64)
-	if err != nil {
-		level.Error(e.client.logger).Log("msg", "Error format total", "err", err)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) < 2 {
+		return 0, 0, fmt.Errorf("unexpected df output: %q", stdout.String())
 	}
-	data.Storage.Total = data.Storage.Total / mb
-
-	ch <- prometheus.MustNewConstMetric(
-		systemVolumes, prometheus.GaugeValue, data.Storage.Total, "total",
-	)
-	ch <- prometheus.MustNewConstMetric(
-		systemVolumes, prometheus.GaugeValue, data.Storage.Free, "free",
-	)
-
-	return true
+	values := strings.Fields(lines[1])
+	if len(values) < 4 {
+		return 0, 0, fmt.Errorf("unexpected df output: %q", stdout.String())
+	}
+	// df -P counts in 1024 byte blocks.
+	if free, err = strconv.ParseUint(values[3], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("error format free: %s", err)
+	}
+	if total, err = strconv.ParseUint(values[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("error format total: %s", err)
+	}
+	return total * 1024, free * 1024, nil
 }
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	// scanSummarySchema is the first schema version, Harbor 2.2, with the
+	// severity counts in scan_report.
+	scanSummarySchema = 50
+
+	queryScanReports = `
+SELECT
+	p.name as project_name,
+	a.repository_name as repo_name,
+	coalesce(sr.status, '') as status,
+	coalesce(sr.critical_cnt, 0) as critical,
+	coalesce(sr.high_cnt, 0) as high,
+	coalesce(sr.medium_cnt, 0) as medium,
+	coalesce(sr.low_cnt, 0) as low,
+	coalesce(sr.none_cnt, 0) as none,
+	coalesce(sr.unknown_cnt, 0) as unknown,
+	coalesce(sr.fixable_cnt, 0) as fixable
+FROM
+	artifact as a
+	JOIN project as p ON p.project_id = a.project_id
+	LEFT JOIN scan_report as sr ON sr.digest = a.digest
+	AND sr.mime_type LIKE 'application/vnd.security.vulnerability.report%'
+	AND sr.registration_uuid = (
+		SELECT uuid FROM scanner_registration WHERE is_default LIMIT 1
+	)
+WHERE
+	NOT EXISTS (
+		SELECT 1 FROM artifact_reference as ar WHERE ar.child_id = a.id
+	);`
+)
+
+var (
+	vulnerabilitiesSource = kingpin.Flag("collector.vulnerabilities.source", "Where the vulnerabilities collector reads scan results from: auto, database or api. auto prefers the database.").Default("auto").Enum("auto", "database", "api")
+	vulnerabilitiesLevel  = kingpin.Flag("collector.vulnerabilities.level", "Aggregation level of the vulnerabilities collector: repository or project.").Default("repository").Enum("repository", "project")
+
+	// severities are the severity label values, most severe first.
+	severities = []string{"critical", "high", "medium", "low", "none", "unknown"}
+	// scanStatuses are the scan_status label values.
+	scanStatuses = []string{"not_scanned", "running", "error", "success"}
+)
+
+func init() {
+	registerCollector("vulnerabilities", defaultDisabled, newVulnerabilitiesCollector)
+}
+
+type vulnerabilitiesCollector struct {
+	client       HarborClient
+	pg           Postgres
+	filter       projectFilter
+	logger       log.Logger
+	source       string
+	perRepo      bool
+	bySeverity   *prometheus.Desc
+	vulnCount    *prometheus.Desc
+	fixableCount *prometheus.Desc
+	scanStatus   *prometheus.Desc
+}
+
+func newVulnerabilitiesCollector(e *Exporter) (Collector, error) {
+	settings := e.settings["vulnerabilities"]
+	source := settings.source
+	apiErr := e.client.Require(harbor.CapArtifacts)
+	switch {
+	case source == "database" && e.pg.db == nil:
+		return nil, &unavailableError{"no database configured"}
+	case source == "api" && apiErr != nil:
+		return nil, apiErr
+	case source == "auto" && e.pg.db == nil && apiErr != nil:
+		return nil, apiErr
+	}
+	labels := []string{"project", "repository"}
+	return &vulnerabilitiesCollector{
+		client:  e.client,
+		pg:      e.pg,
+		filter:  e.filter,
+		logger:  e.logger,
+		source:  source,
+		perRepo: settings.level == "repository",
+		bySeverity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "artifacts_vulnerability_severity"),
+			"Number of scanned artifacts by their most severe vulnerability.",
+			append(labels, "severity"), nil,
+		),
+		vulnCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "artifacts_vulnerabilities"),
+			"Number of vulnerabilities found in the artifacts, by severity.",
+			append(labels, "severity"), nil,
+		),
+		fixableCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "artifacts_vulnerabilities_fixable"),
+			"Number of vulnerabilities found in the artifacts that have a fix.",
+			labels, nil,
+		),
+		scanStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, e.opts.instance, "artifacts_scan_status"),
+			"Number of artifacts by the status of their last scan.",
+			append(labels, "status"), nil,
+		),
+	}, nil
+}
+
+// scanResult is the outcome of the last scan of one artifact.
+type scanResult struct {
+	status string
+	// vulns is indexed like severities.
+	vulns   [6]int64
+	fixable int64
+}
+
+// vulnStats aggregates the scan results of a project or repository.
+type vulnStats struct {
+	bySeverity [6]float64
+	vulns      [6]float64
+	fixable    float64
+	status     [4]float64
+}
+
+func (s *vulnStats) add(r scanResult) {
+	status := scanStatus(r.status)
+	for i, name := range scanStatuses {
+		if name == status {
+			s.status[i]++
+		}
+	}
+	if status != "success" {
+		return
+	}
+	highest := len(severities) - 1
+	for i, n := range r.vulns {
+		s.vulns[i] += float64(n)
+		if n > 0 && i < highest {
+			highest = i
+		}
+	}
+	// Nothing found at all is reported as none rather than unknown.
+	if highest == len(severities)-1 && r.vulns[highest] == 0 {
+		highest = len(severities) - 2
+	}
+	s.bySeverity[highest]++
+	s.fixable += float64(r.fixable)
+}
+
+// scanStatus maps Harbor's job status to one of scanStatuses.
+func scanStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "":
+		return "not_scanned"
+	case "pending", "scheduled", "running":
+		return "running"
+	case "success":
+		return "success"
+	default:
+		return "error"
+	}
+}
+
+// severityIndex returns the index in severities of a Harbor severity.
+func severityIndex(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 0
+	case "high":
+		return 1
+	case "medium":
+		return 2
+	case "low":
+		return 3
+	case "none", "negligible":
+		return 4
+	default:
+		return 5
+	}
+}
+
+func (c *vulnerabilitiesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	stats := make(map[[2]string]*vulnStats)
+	add := func(project, repo string, r scanResult) {
+		if !c.filter.match(project) {
+			return
+		}
+		if !c.perRepo {
+			repo = ""
+		}
+		key := [2]string{project, repo}
+		if stats[key] == nil {
+			stats[key] = &vulnStats{}
+		}
+		stats[key].add(r)
+	}
+
+	useDB, err := c.useDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	if useDB {
+		err = c.fromDatabase(ctx, add)
+	} else {
+		err = c.fromAPI(ctx, add)
+	}
+	if err != nil {
+		return err
+	}
+
+	for key, s := range stats {
+		for i, severity := range severities {
+			ch <- prometheus.MustNewConstMetric(
+				c.bySeverity, prometheus.GaugeValue, s.bySeverity[i], key[0], key[1], severity,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.vulnCount, prometheus.GaugeValue, s.vulns[i], key[0], key[1], severity,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.fixableCount, prometheus.GaugeValue, s.fixable, key[0], key[1],
+		)
+		for i, status := range scanStatuses {
+			ch <- prometheus.MustNewConstMetric(
+				c.scanStatus, prometheus.GaugeValue, s.status[i], key[0], key[1], status,
+			)
+		}
+	}
+	return nil
+}
+
+// useDatabase decides whether the scan results are read from the database.
+// In auto mode the API is used when the schema lacks the severity counts.
+func (c *vulnerabilitiesCollector) useDatabase(ctx context.Context) (bool, error) {
+	if c.source == "api" || c.pg.db == nil {
+		return false, nil
+	}
+	version, dirty, err := readSchemaVersion(ctx, c.pg.db)
+	if err == nil && dirty {
+		err = fmt.Errorf("harbor schema migration %d did not complete (dirty)", version)
+	}
+	if err == nil && version < scanSummarySchema {
+		err = fmt.Errorf("harbor schema version %d has no severity counts in scan_report, harbor 2.2 or newer is needed", version)
+	}
+	if err != nil {
+		if c.source == "database" {
+			return false, err
+		}
+		level.Debug(c.logger).Log("msg", "Reading scan results from the API", "reason", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *vulnerabilitiesCollector) fromDatabase(ctx context.Context, add func(project, repo string, r scanResult)) error {
+	rows, err := c.pg.db.QueryContext(ctx, queryScanReports)
+	if err != nil {
+		return fmt.Errorf("error querying scan reports: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			project, repo string
+			r             scanResult
+		)
+		if err := rows.Scan(&project, &repo, &r.status,
+			&r.vulns[0], &r.vulns[1], &r.vulns[2], &r.vulns[3], &r.vulns[4], &r.vulns[5],
+			&r.fixable); err != nil {
+			return fmt.Errorf("error reading scan reports: %s", err)
+		}
+		add(project, repo, r)
+	}
+	return rows.Err()
+}
+
+func (c *vulnerabilitiesCollector) fromAPI(ctx context.Context, add func(project, repo string, r scanResult)) error {
+	projects, err := c.client.ListProjects(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving projects: %s", err)
+	}
+	for _, project := range projects {
+		if !c.filter.match(project.Name) {
+			continue
+		}
+		repos, err := c.client.ListRepositories(ctx, project, nil)
+		if err != nil {
+			return fmt.Errorf("error retrieving repositories of %s: %s", project.Name, err)
+		}
+		for _, repo := range repos {
+			artifacts, err := c.client.ListArtifacts(ctx, repo.Name, &harbor.ArtifactListOptions{WithScanOverview: true})
+			if err != nil {
+				return fmt.Errorf("error retrieving artifacts of %s: %s", repo.Name, err)
+			}
+			for _, artifact := range artifacts {
+				add(project.Name, repo.Name, apiScanResult(artifact))
+			}
+		}
+	}
+	return nil
+}
+
+// apiScanResult extracts the vulnerability report from the scan overview of
+// an artifact.
+func apiScanResult(artifact harbor.Artifact) scanResult {
+	var r scanResult
+	for mimeType, overview := range artifact.ScanOverview {
+		if !strings.Contains(mimeType, "vuln") {
+			continue
+		}
+		r.status = overview.ScanStatus
+		if overview.Summary != nil {
+			for severity, n := range overview.Summary.Summary {
+				r.vulns[severityIndex(severity)] += n
+			}
+			r.fixable = overview.Summary.Fixable
+		}
+		break
+	}
+	return r
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/c4po/harbor_exporter/harbor"
+	"github.com/go-kit/kit/log"
+)
+
+func TestVulnStatsAdd(t *testing.T) {
+	var s vulnStats
+	for _, r := range []scanResult{
+		{status: "Success", vulns: [6]int64{1, 2, 0, 3, 0, 0}, fixable: 2},
+		{status: "Success", vulns: [6]int64{0, 0, 1, 0, 5, 0}, fixable: 1},
+		// Nothing found counts as none.
+		{status: "Success"},
+		// Only unknown findings count as unknown.
+		{status: "Success", vulns: [6]int64{0, 0, 0, 0, 0, 2}},
+		// Counts of unfinished or failed scans are ignored.
+		{status: "Running", vulns: [6]int64{9, 9, 9, 9, 9, 9}, fixable: 9},
+		{status: "Error", vulns: [6]int64{9}},
+		{status: ""},
+	} {
+		s.add(r)
+	}
+	want := vulnStats{
+		bySeverity: [6]float64{1, 0, 1, 0, 1, 1},
+		vulns:      [6]float64{1, 2, 1, 3, 5, 2},
+		fixable:    3,
+		// not_scanned, running, error, success
+		status: [4]float64{1, 1, 1, 4},
+	}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+}
+
+func TestAPIScanResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		overview string
+		want     scanResult
+	}{
+		{
+			name:     "not scanned",
+			overview: `{}`,
+			want:     scanResult{},
+		},
+		{
+			name: "success",
+			overview: `{
+				"application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0": {
+					"scan_status": "Success",
+					"severity": "Critical",
+					"summary": {"total": 9, "fixable": 4, "summary": {"Critical": 1, "High": 2, "Medium": 1, "Low": 3, "Negligible": 1, "Unknown": 1}}
+				}
+			}`,
+			want: scanResult{status: "Success", vulns: [6]int64{1, 2, 1, 3, 1, 1}, fixable: 4},
+		},
+		{
+			name: "running",
+			overview: `{
+				"application/vnd.security.vulnerability.report; version=1.1": {"scan_status": "Running"}
+			}`,
+			want: scanResult{status: "Running"},
+		},
+		{
+			name: "other reports",
+			overview: `{
+				"application/vnd.security.sbom.report+json; version=1.0": {"scan_status": "Error"},
+				"application/vnd.security.vulnerability.report; version=1.1": {
+					"scan_status": "Success",
+					"summary": {"total": 2, "fixable": 0, "summary": {"None": 2}}
+				}
+			}`,
+			want: scanResult{status: "Success", vulns: [6]int64{0, 0, 0, 0, 2, 0}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var artifact harbor.Artifact
+			if err := json.Unmarshal([]byte(`{"scan_overview": `+test.overview+`}`), &artifact); err != nil {
+				t.Fatal(err)
+			}
+			if got := apiScanResult(artifact); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func newTestVulnCollector(t *testing.T, hc HarborClient, db *sql.DB, source, level string) Collector {
+	t.Helper()
+	settings := settingsFor("vulnerabilities", &Config{})
+	settings.source, settings.level = source, level
+	c, err := newVulnerabilitiesCollector(&Exporter{
+		client:   hc,
+		pg:       Postgres{db: db},
+		logger:   log.NewNopLogger(),
+		settings: map[string]collectorSettings{"vulnerabilities": settings},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// vulnHarbor serves two repositories of the library project with scanned
+// and unscanned artifacts.
+func vulnHarbor(w http.ResponseWriter, r *http.Request) {
+	const vuln = "application/vnd.security.vulnerability.report; version=1.1"
+	switch r.URL.Path {
+	case "/projects":
+		w.Write([]byte(`[{"project_id": 1, "name": "library"}]`))
+	case "/projects/library/repositories":
+		w.Write([]byte(`[{"id": 1, "name": "library/nginx"}, {"id": 2, "name": "library/redis"}]`))
+	case "/projects/library/repositories/nginx/artifacts":
+		w.Write([]byte(`[
+			{"digest": "sha256:1", "scan_overview": {"` + vuln + `": {"scan_status": "Success", "summary": {"fixable": 2, "summary": {"Critical": 1, "High": 2, "Low": 3}}}}},
+			{"digest": "sha256:2", "scan_overview": {"` + vuln + `": {"scan_status": "Success", "summary": {"summary": {}}}}},
+			{"digest": "sha256:3"}
+		]`))
+	case "/projects/library/repositories/redis/artifacts":
+		w.Write([]byte(`[
+			{"digest": "sha256:4", "scan_overview": {"` + vuln + `": {"scan_status": "Error"}}},
+			{"digest": "sha256:5", "scan_overview": {"` + vuln + `": {"scan_status": "Running"}}},
+			{"digest": "sha256:6", "scan_overview": {"` + vuln + `": {"scan_status": "Success", "summary": {"summary": {"Negligible": 4, "Unknown": 1}}}}}
+		]`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// vulnRows are the scan reports of vulnHarbor as the database has them.
+var vulnRows = [][]driver.Value{
+	{"library", "library/nginx", "Success", int64(1), int64(2), int64(0), int64(3), int64(0), int64(0), int64(2)},
+	{"library", "library/nginx", "Success", int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)},
+	{"library", "library/nginx", "", int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)},
+	{"library", "library/redis", "Error", int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)},
+	{"library", "library/redis", "Running", int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)},
+	{"library", "library/redis", "Success", int64(0), int64(0), int64(0), int64(0), int64(4), int64(1), int64(0)},
+}
+
+func vulnDB(t *testing.T) *sql.DB {
+	tables := schemaTable(100, false)
+	tables["scan_report"] = testTable{
+		columns: []string{"project_name", "repo_name", "status", "critical", "high", "medium", "low", "none", "unknown", "fixable"},
+		rows:    vulnRows,
+	}
+	return testDB(t, tables)
+}
+
+func TestVulnerabilitiesPerRepository(t *testing.T) {
+	hc, srv := testHarbor(vulnHarbor)
+	defer srv.Close()
+	db := vulnDB(t)
+	defer db.Close()
+
+	for _, source := range []string{"api", "database"} {
+		t.Run(source, func(t *testing.T) {
+			samples, err := collect(t, newTestVulnCollector(t, hc, db, source, "repository"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []struct {
+				name   string
+				labels []string
+				value  float64
+			}{
+				{"harbor_artifacts_vulnerability_severity", []string{"repository", "library/nginx", "severity", "critical"}, 1},
+				{"harbor_artifacts_vulnerability_severity", []string{"repository", "library/nginx", "severity", "none"}, 1},
+				{"harbor_artifacts_vulnerability_severity", []string{"repository", "library/nginx", "severity", "high"}, 0},
+				{"harbor_artifacts_vulnerability_severity", []string{"repository", "library/redis", "severity", "none"}, 1},
+				{"harbor_artifacts_vulnerabilities", []string{"repository", "library/nginx", "severity", "high"}, 2},
+				{"harbor_artifacts_vulnerabilities", []string{"repository", "library/nginx", "severity", "low"}, 3},
+				{"harbor_artifacts_vulnerabilities", []string{"repository", "library/redis", "severity", "none"}, 4},
+				{"harbor_artifacts_vulnerabilities", []string{"repository", "library/redis", "severity", "unknown"}, 1},
+				{"harbor_artifacts_vulnerabilities_fixable", []string{"repository", "library/nginx"}, 2},
+				{"harbor_artifacts_vulnerabilities_fixable", []string{"repository", "library/redis"}, 0},
+				{"harbor_artifacts_scan_status", []string{"repository", "library/nginx", "status", "success"}, 2},
+				{"harbor_artifacts_scan_status", []string{"repository", "library/nginx", "status", "not_scanned"}, 1},
+				{"harbor_artifacts_scan_status", []string{"repository", "library/redis", "status", "error"}, 1},
+				{"harbor_artifacts_scan_status", []string{"repository", "library/redis", "status", "running"}, 1},
+			} {
+				v, ok := find(samples, want.name, want.labels...)
+				if !ok || v != want.value {
+					t.Errorf("%s%v = %v (found %v), want %v", want.name, want.labels, v, ok, want.value)
+				}
+			}
+		})
+	}
+}
+
+func TestVulnerabilitiesPerProject(t *testing.T) {
+	hc, srv := testHarbor(vulnHarbor)
+	defer srv.Close()
+	db := vulnDB(t)
+	defer db.Close()
+
+	for _, source := range []string{"api", "database"} {
+		t.Run(source, func(t *testing.T) {
+			samples, err := collect(t, newTestVulnCollector(t, hc, db, source, "project"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := count(samples, "harbor_artifacts_vulnerabilities_fixable"); n != 1 {
+				t.Errorf("got %d fixable series, want one for the project", n)
+			}
+			if v, _ := find(samples, "harbor_artifacts_vulnerability_severity", "project", "library", "repository", "", "severity", "none"); v != 2 {
+				t.Errorf("artifacts with no severity = %v, want 2", v)
+			}
+			if v, _ := find(samples, "harbor_artifacts_scan_status", "project", "library", "status", "success"); v != 3 {
+				t.Errorf("successful scans = %v, want 3", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	dbMaxOpenConns     = kingpin.Flag("database.max-open-conns", "Maximum number of open connections per database.").Default("3").Int()
+	dbMaxIdleConns     = kingpin.Flag("database.max-idle-conns", "Maximum number of idle connections per database.").Default("1").Int()
+	dbConnMaxLifetime  = kingpin.Flag("database.conn-max-lifetime", "Close database connections after this time, 0 to keep them forever.").Default("5m").Duration()
+	dbStatementTimeout = kingpin.Flag("database.statement-timeout", "Server side statement_timeout of the exporter's database sessions, 0 to use the server default.").Default("30s").Duration()
+
+	// pools holds the connection pools of every exporter, Harbor is connected
+	// to only once per DSN no matter how often the config is reloaded.
+	pools = newPoolRegistry()
+)
+
+func init() {
+	prometheus.MustRegister(pools)
+}
+
+// poolOpts configure a connection pool.
+type poolOpts struct {
+	maxOpenConns     int
+	maxIdleConns     int
+	connMaxLifetime  time.Duration
+	statementTimeout time.Duration
+}
+
+// pool is a connection pool shared by all exporters using the same DSN.
+type pool struct {
+	db   *sql.DB
+	name string
+	refs int
+}
+
+// poolRegistry hands out the shared connection pools and exports their
+// statistics.
+type poolRegistry struct {
+	mtx   sync.Mutex
+	pools map[string]*pool
+
+	openConns         *prometheus.Desc
+	inUseConns        *prometheus.Desc
+	idleConns         *prometheus.Desc
+	maxOpenConns      *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+func newPoolRegistry() *poolRegistry {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "db_"+name),
+			help,
+			[]string{"database"}, nil,
+		)
+	}
+	return &poolRegistry{
+		pools:             make(map[string]*pool),
+		openConns:         desc("open_connections", "Number of established connections, in use and idle."),
+		inUseConns:        desc("in_use_connections", "Number of connections currently in use."),
+		idleConns:         desc("idle_connections", "Number of idle connections."),
+		maxOpenConns:      desc("max_open_connections", "Maximum number of open connections."),
+		waitCount:         desc("wait_count_total", "Total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "Total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "Total number of connections closed due to the idle limit."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Total number of connections closed due to the lifetime limit."),
+	}
+}
+
+// sessionDSN returns dsn with the exporter's session settings added. Settings
+// already present in dsn win.
+func sessionDSN(dsn string, statementTimeout time.Duration) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", err
+		}
+	}
+	settings := "application_name=harbor_exporter"
+	if statementTimeout > 0 {
+		settings += fmt.Sprintf(" statement_timeout=%d", statementTimeout/time.Millisecond)
+	}
+	return settings + " " + dsn, nil
+}
+
+// acquire returns the pool for dsn, opening it if needed, and applies opts to
+// it. name labels the pool metrics. Every acquire has to be matched by a
+// release.
+func (r *poolRegistry) acquire(name, dsn string, opts poolOpts) (*sql.DB, error) {
+	dsn, err := sessionDSN(dsn, opts.statementTimeout)
+	if err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	p, ok := r.pools[dsn]
+	if !ok {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, err
+		}
+		p = &pool{db: db, name: name}
+		r.pools[dsn] = p
+	}
+	p.refs++
+	p.db.SetMaxOpenConns(opts.maxOpenConns)
+	p.db.SetMaxIdleConns(opts.maxIdleConns)
+	p.db.SetConnMaxLifetime(opts.connMaxLifetime)
+	return p.db, nil
+}
+
+// release gives back a pool obtained with acquire and closes it once nobody
+// uses it anymore.
+func (r *poolRegistry) release(db *sql.DB) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for dsn, p := range r.pools {
+		if p.db != db {
+			continue
+		}
+		if p.refs--; p.refs <= 0 {
+			p.db.Close()
+			delete(r.pools, dsn)
+		}
+		return
+	}
+}
+
+// closeAll closes every pool, terminating the sessions on the server.
+func (r *poolRegistry) closeAll() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for dsn, p := range r.pools {
+		p.db.Close()
+		delete(r.pools, dsn)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (r *poolRegistry) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.openConns
+	ch <- r.inUseConns
+	ch <- r.idleConns
+	ch <- r.maxOpenConns
+	ch <- r.waitCount
+	ch <- r.waitDuration
+	ch <- r.maxIdleClosed
+	ch <- r.maxLifetimeClosed
+}
+
+// Collect exports the sql.DBStats of every pool. It implements
+// prometheus.Collector.
+func (r *poolRegistry) Collect(ch chan<- prometheus.Metric) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, p := range r.pools {
+		stats := p.db.Stats()
+		ch <- prometheus.MustNewConstMetric(r.openConns, prometheus.GaugeValue, float64(stats.OpenConnections), p.name)
+		ch <- prometheus.MustNewConstMetric(r.inUseConns, prometheus.GaugeValue, float64(stats.InUse), p.name)
+		ch <- prometheus.MustNewConstMetric(r.idleConns, prometheus.GaugeValue, float64(stats.Idle), p.name)
+		ch <- prometheus.MustNewConstMetric(r.maxOpenConns, prometheus.GaugeValue, float64(stats.MaxOpenConnections), p.name)
+		ch <- prometheus.MustNewConstMetric(r.waitCount, prometheus.CounterValue, float64(stats.WaitCount), p.name)
+		ch <- prometheus.MustNewConstMetric(r.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), p.name)
+		ch <- prometheus.MustNewConstMetric(r.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), p.name)
+		ch <- prometheus.MustNewConstMetric(r.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), p.name)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// dsnSettings parses a key=value DSN the way lib/pq does for plain values:
+// a later setting overrides an earlier one.
+func dsnSettings(dsn string) map[string]string {
+	settings := make(map[string]string)
+	for _, field := range strings.Fields(dsn) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 2 {
+			settings[kv[0]] = kv[1]
+		}
+	}
+	return settings
+}
+
+func TestSessionDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		timeout time.Duration
+		want    map[string]string
+		absent  []string
+	}{
+		{
+			name:    "key value",
+			dsn:     "host=db user=harbor dbname=registry",
+			timeout: 30 * time.Second,
+			want: map[string]string{
+				"host": "db", "user": "harbor", "dbname": "registry",
+				"application_name": "harbor_exporter", "statement_timeout": "30000",
+			},
+		},
+		{
+			name:    "url",
+			dsn:     "postgres://harbor:secret@db:6432/registry?sslmode=require",
+			timeout: 1500 * time.Millisecond,
+			want: map[string]string{
+				"host": "db", "port": "6432", "user": "harbor", "password": "secret",
+				"dbname": "registry", "sslmode": "require",
+				"application_name": "harbor_exporter", "statement_timeout": "1500",
+			},
+		},
+		{
+			name:    "postgresql url",
+			dsn:     "postgresql://db/registry",
+			timeout: time.Second,
+			want:    map[string]string{"host": "db", "dbname": "registry", "statement_timeout": "1000"},
+		},
+		{
+			name:    "key value settings win",
+			dsn:     "host=db application_name=ops statement_timeout=5000",
+			timeout: 30 * time.Second,
+			want:    map[string]string{"application_name": "ops", "statement_timeout": "5000"},
+		},
+		{
+			name:    "url settings win",
+			dsn:     "postgres://db/registry?application_name=ops&statement_timeout=5000",
+			timeout: 30 * time.Second,
+			want:    map[string]string{"application_name": "ops", "statement_timeout": "5000"},
+		},
+		{
+			name:   "server default timeout",
+			dsn:    "host=db",
+			want:   map[string]string{"application_name": "harbor_exporter"},
+			absent: []string{"statement_timeout"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dsn, err := sessionDSN(test.dsn, test.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings := dsnSettings(dsn)
+			for key, want := range test.want {
+				if got := settings[key]; got != want {
+					t.Errorf("%s = %q in %q, want %q", key, got, dsn, want)
+				}
+			}
+			for _, key := range test.absent {
+				if _, ok := settings[key]; ok {
+					t.Errorf("%s set in %q", key, dsn)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionDSNInvalidURL(t *testing.T) {
+	if _, err := sessionDSN("postgres://db:port/registry", 0); err == nil {
+		t.Error("invalid URL accepted")
+	}
+}
+
+// sql.Open does not connect, so the registry can be tested without a
+// database.
+func TestPoolRegistryRefcount(t *testing.T) {
+	r := newPoolRegistry()
+	defer r.closeAll()
+	opts := poolOpts{maxOpenConns: 3, maxIdleConns: 1}
+
+	a, err := r.acquire("registry", "host=db dbname=registry", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.acquire("registry", "host=db dbname=registry", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("same DSN got two pools")
+	}
+	other, err := r.acquire("postgres", "host=db dbname=postgres", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == a {
+		t.Fatal("different DSNs share a pool")
+	}
+	if n := len(r.pools); n != 2 {
+		t.Fatalf("got %d pools, want 2", n)
+	}
+
+	// The shared pool stays open until its last user is gone.
+	r.release(a)
+	if n := len(r.pools); n != 2 {
+		t.Fatalf("got %d pools after the first release, want 2", n)
+	}
+	if got := b.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("max open connections = %d, want 3", got)
+	}
+	r.release(b)
+	if n := len(r.pools); n != 1 {
+		t.Fatalf("got %d pools after the last release, want 1", n)
+	}
+	if err := b.Ping(); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("ping after the last release = %v, want the pool closed", err)
+	}
+
+	// Releasing an unknown or already released pool changes nothing.
+	r.release(b)
+	if n := len(r.pools); n != 1 {
+		t.Fatalf("got %d pools after releasing twice, want 1", n)
+	}
+	r.closeAll()
+	if n := len(r.pools); n != 0 {
+		t.Errorf("got %d pools after closeAll, want 0", n)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultProbeTimeout is used when neither the module nor Prometheus tell how
+// long a probe may take.
+const defaultProbeTimeout = 10 * time.Second
+
+// minProbeTimeout is the least time a probe gets, however short the scrape
+// timeout is.
+const minProbeTimeout = 100 * time.Millisecond
+
+// newProbeExporter returns an Exporter for a single probe of target. It only
+// talks to the Harbor API, so collectors that need the database or the
+// Kubernetes API can not be used by modules.
+func newProbeExporter(ctx context.Context, target string, module Module, conf *Config, logger log.Logger) (*Exporter, error) {
+	opts := harborOpts{
+		uri:      target,
+		username: module.Username,
+		password: module.Password,
+		insecure: module.TLSConfig.InsecureSkipVerify,
+		caFile:   module.TLSConfig.CAFile,
+	}
+	hc, err := newHarborClient(ctx, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		ctx:        ctx,
+		client:     hc,
+		opts:       hc.opts,
+		logger:     logger,
+		collectors: make(map[string]Collector),
+		cache:      newSnapshotCache(),
+		up:         newUpDesc(""),
+	}
+	settings := make(map[string]collectorSettings)
+	for _, name := range module.Collectors {
+		// Probes always collect live.
+		s := settingsFor(name, conf)
+		s.interval = 0
+		settings[name] = s
+	}
+	if err := e.initCollectors(settings); err != nil {
+		hc.HTTPClient.CloseIdleConnections()
+		return nil, err
+	}
+	return e, nil
+}
+
+// probeTimeout returns how long a probe may take, preferring the module
+// setting over the scrape timeout announced by Prometheus.
+func probeTimeout(r *http.Request, module Module) time.Duration {
+	if module.Timeout > 0 {
+		return module.Timeout
+	}
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err == nil && seconds > 0 {
+			// Leave some room to hand the result back to Prometheus.
+			timeout := time.Duration((seconds - 0.5) * float64(time.Second))
+			if timeout < minProbeTimeout {
+				timeout = minProbeTimeout
+			}
+			return timeout
+		}
+	}
+	return defaultProbeTimeout
+}
+
+func probeHandler(w http.ResponseWriter, r *http.Request, conf *Config, logger log.Logger) {
+	params := r.URL.Query()
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	moduleName := params.Get("module")
+	if moduleName == "" {
+		moduleName = "default"
+	}
+	module, ok := conf.Modules[moduleName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown module %q", moduleName), http.StatusBadRequest)
+		return
+	}
+	logger = log.With(logger, "module", moduleName, "target", target)
+	// Checked before anything is sent, the module's credentials must only
+	// reach the Harbors it is meant for.
+	if !module.allows(target) {
+		level.Warn(logger).Log("msg", "Target not allowed by module")
+		http.Error(w, fmt.Sprintf("Target %q not allowed by module %q", target, moduleName), http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout(r, module))
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	exporter, err := newProbeExporter(ctx, target, module, conf, logger)
+	if err != nil {
+		// An unreachable target is a probe result, not a failed request.
+		level.Error(logger).Log("msg", "Error creating the probe exporter", "err", err)
+		registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "up",
+			Help:      "Was the last query of harbor successful.",
+		}, func() float64 { return 0 }))
+	} else {
+		defer exporter.client.HTTPClient.CloseIdleConnections()
+		registry.MustRegister(exporter)
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog: &promHTTPLogger{logger: logger},
+	}).ServeHTTP(w, r)
+}
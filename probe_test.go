@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestModuleAllows(t *testing.T) {
+	conf := &Config{Modules: map[string]Module{
+		"prod":      {Username: "admin", Password: "secret", Targets: `harbor-(prod|dr)\.example\.com(:443)?`},
+		"anonymous": {},
+	}}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		module, target string
+		want           bool
+	}{
+		{"prod", "https://harbor-prod.example.com", true},
+		{"prod", "harbor-dr.example.com:443", true},
+		{"prod", "https://harbor-prod.example.com/some/path", true},
+		{"prod", "https://evil.example.org", false},
+		{"prod", "https://harbor-prod.example.com.evil.org", false},
+		{"prod", "https://harbor-prod.example.com@evil.org", false},
+		{"prod", "https://harbor-prod.example.com:8443", false},
+		{"prod", "://", false},
+		{"anonymous", "https://anything.example.org", true},
+	} {
+		if got := conf.Modules[tc.module].allows(tc.target); got != tc.want {
+			t.Errorf("%s allows %q = %v, want %v", tc.module, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestModuleTargetsRequired(t *testing.T) {
+	conf := &Config{Modules: map[string]Module{"prod": {Username: "admin", Password: "secret"}}}
+	if err := conf.validate(); err == nil {
+		t.Error("module with credentials but without targets was accepted")
+	}
+	conf = &Config{Modules: map[string]Module{"prod": {Username: "admin", Targets: "("}}}
+	if err := conf.validate(); err == nil {
+		t.Error("module with an invalid targets pattern was accepted")
+	}
+}
+
+// A target outside the allow-list never sees a request, let alone the
+// credentials.
+func TestProbeRejectsTarget(t *testing.T) {
+	var hits int32
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer evil.Close()
+
+	conf := &Config{Modules: map[string]Module{
+		"prod": {Username: "admin", Password: "secret", Targets: `harbor\.example\.com`},
+	}}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/probe?module=prod&target="+evil.URL, nil)
+	probeHandler(rec, req, conf, log.NewLogfmtLogger(&logs))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("target received %d requests", n)
+	}
+	// The warning tells which module refused which target.
+	for _, want := range []string{"module=prod", "target=" + evil.URL} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q lacks %s", logs.String(), want)
+		}
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	tests := []struct {
+		module Module
+		header string
+		want   time.Duration
+	}{
+		{want: defaultProbeTimeout},
+		{header: "15", want: 14500 * time.Millisecond},
+		{header: "2.5", want: 2 * time.Second},
+		// Too short to leave room, or not a timeout at all.
+		{header: "0.5", want: minProbeTimeout},
+		{header: "0.2", want: minProbeTimeout},
+		{header: "0", want: defaultProbeTimeout},
+		{header: "soon", want: defaultProbeTimeout},
+		// The module wins.
+		{module: Module{Timeout: 3 * time.Second}, header: "15", want: 3 * time.Second},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+		if test.header != "" {
+			req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", test.header)
+		}
+		if got := probeTimeout(req, test.module); got != test.want {
+			t.Errorf("timeout %q with module %v = %s, want %s", test.header, test.module.Timeout, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reloadTimeout bounds building the Exporter of a reload, which talks to
+// Harbor.
+const reloadTimeout = 30 * time.Second
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful.",
+	})
+	configReloadSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(configReloadSuccess, configReloadSeconds)
+}
+
+// reloadableExporter serves the metrics of the current Exporter and replaces
+// it with a freshly built one whenever the configuration is reloaded. A reload
+// that fails leaves the running Exporter untouched.
+type reloadableExporter struct {
+	configFile string
+	opts       harborOpts
+	logger     log.Logger
+
+	// ctx is cancelled on shutdown, aborting a reload in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// reloadMtx serializes reloads, mtx guards the fields below.
+	reloadMtx sync.Mutex
+	mtx       sync.RWMutex
+	exporter  *Exporter
+	conf      *Config
+	stop      context.CancelFunc
+	closed    bool
+}
+
+func newReloadableExporter(configFile string, opts harborOpts, logger log.Logger) *reloadableExporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &reloadableExporter{
+		configFile: configFile,
+		opts:       opts,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// reload reads the config file, builds a new Exporter from it and swaps it in.
+func (r *reloadableExporter) reload() error {
+	r.reloadMtx.Lock()
+	defer r.reloadMtx.Unlock()
+
+	conf, err := loadConfig(r.configFile)
+	if err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, reloadTimeout)
+	exporter, err := NewExporter(ctx, r.opts, conf, r.logger)
+	cancel()
+	if err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+
+	r.mtx.Lock()
+	if r.closed {
+		r.mtx.Unlock()
+		exporter.close()
+		return errShutdown
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	exporter.startCollectionLoop(ctx)
+	old, stop := r.exporter, r.stop
+	r.exporter, r.conf, r.stop = exporter, conf, cancel
+	r.mtx.Unlock()
+	if stop != nil {
+		stop()
+		old.close()
+	}
+
+	configReloadSuccess.Set(1)
+	configReloadSeconds.SetToCurrentTime()
+	level.Info(r.logger).Log("msg", "Loaded configuration", "file", r.configFile)
+	return nil
+}
+
+// errShutdown is returned by a reload that raced with shutdown.
+var errShutdown = errors.New("exporter is shutting down")
+
+// shutdown stops the background collection and releases the current
+// Exporter. It does not wait for a reload in flight, which is aborted and
+// discards what it built.
+func (r *reloadableExporter) shutdown() {
+	r.cancel()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.closed = true
+	if r.stop != nil {
+		r.stop()
+		r.exporter.close()
+		r.exporter, r.stop = nil, nil
+	}
+}
+
+// config returns the configuration currently in use.
+func (r *reloadableExporter) config() *Config {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.conf
+}
+
+// Describe sends nothing, which makes this an unchecked collector: the
+// metrics exported may change with every reload. It implements
+// prometheus.Collector.
+func (r *reloadableExporter) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect delegates to the current Exporter. It implements
+// prometheus.Collector.
+func (r *reloadableExporter) Collect(ch chan<- prometheus.Metric) {
+	r.mtx.RLock()
+	exporter := r.exporter
+	r.mtx.RUnlock()
+	if exporter != nil {
+		exporter.Collect(ch)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// hangingHarbor accepts connections but never answers until the client gives
+// up.
+func hangingHarbor() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+}
+
+// A shutdown while a reload hangs on an unreachable Harbor neither waits for
+// it nor lets it install an Exporter afterwards.
+func TestShutdownDuringReload(t *testing.T) {
+	srv := hangingHarbor()
+	defer srv.Close()
+
+	r := newReloadableExporter("", harborOpts{uri: srv.URL, mode: modeStandalone}, log.NewNopLogger())
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- r.reload() }()
+	// Give the reload time to reach Harbor.
+	time.Sleep(100 * time.Millisecond)
+
+	shut := make(chan struct{})
+	go func() {
+		r.shutdown()
+		close(shut)
+	}()
+	select {
+	case <-shut:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown waited for the reload")
+	}
+
+	select {
+	case err := <-reloaded:
+		if err == nil {
+			t.Error("reload against a hanging Harbor succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload was not aborted by shutdown")
+	}
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if r.exporter != nil {
+		t.Error("reload installed an Exporter after shutdown")
+	}
+}
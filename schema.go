@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// querySet holds the SQL of the repositories collector for one database
+// layout.
+type querySet struct {
+	// release names the Harbor releases the queries work with.
+	release string
+	// minVersion and maxVersion bound the schema_migrations versions of
+	// those releases, maxVersion is exclusive.
+	minVersion, maxVersion int64
+
+	// allRepo returns repo_id, repo_name, pull_count, push_count and
+	// tag_count of every repository. Tags are counted in the same query so
+	// the collector never holds two connections at once.
+	allRepo string
+	// imagePull returns repo_name, tag_name and pull_count of every tag.
+	imagePull string
+	// projectSize returns project_name and size of every project.
+	projectSize string
+}
+
+// querySets covers the schemas of Harbor 1.9 up to 2.14. Harbor numbers its
+// migrations after the release, e.g. 0015_1.10.0 or 0100_2.7.0.
+var querySets = []querySet{
+	{
+		// Tags are rows of artifact, audit records live in access_log.
+		release:    "1.9-1.10",
+		minVersion: 10,
+		maxVersion: 30,
+		allRepo: `
+SELECT
+    r.repository_id as repo_id,
+    r.name as repo_name,
+    r.pull_count as pull_count,
+    count(al.log_id) as push_count,
+    coalesce(t.tag_count, 0) as tag_count
+FROM
+    repository as r
+    JOIN access_log as al ON al.repo_name = r.name
+    LEFT JOIN (
+        SELECT repo, count(id) as tag_count FROM artifact GROUP BY repo
+    ) as t ON t.repo = r.name
+WHERE
+    al.operation = 'push'
+GROUP By
+    r.repository_id,
+    t.tag_count;`,
+		imagePull: `
+SELECT
+	a.repo as repo_name,
+	a.tag as tag_name,
+	count(al.log_id) as pull_count
+FROM
+	access_log as al
+	JOIN artifact as a ON a.repo = al.repo_name
+	AND a.tag = repo_tag
+WHERE
+	al.operation = 'pull'
+GROUP BY
+	a.repo,
+	a.tag;`,
+		projectSize: `
+SELECT
+    p.name as project_name,
+    sum(b.size) as size
+FROM
+    blob AS b
+    JOIN artifact_blob AS ab ON ab.digest_blob = b.digest
+    JOIN artifact AS a ON a.digest = digest_af
+    JOIN repository AS r ON a.repo = r.name
+    JOIN project AS p ON r.project_id = p.project_id
+GROUP BY
+    p.name;`,
+	},
+	{
+		// Tags have their own table, artifacts reference the repository by
+		// name and id, and audit records live in audit_log with the
+		// artifact as "repo:tag" or "repo@digest" in resource. Pushes are
+		// logged as create.
+		release:    "2.0-2.14",
+		minVersion: 30,
+		maxVersion: 180,
+		allRepo: `
+SELECT
+    r.repository_id as repo_id,
+    r.name as repo_name,
+    r.pull_count as pull_count,
+    count(al.id) as push_count,
+    coalesce(t.tag_count, 0) as tag_count
+FROM
+    repository as r
+    JOIN audit_log as al ON split_part(split_part(al.resource, '@', 1), ':', 1) = r.name
+    LEFT JOIN (
+        SELECT repository_id, count(id) as tag_count FROM tag GROUP BY repository_id
+    ) as t ON t.repository_id = r.repository_id
+WHERE
+    al.operation = 'create'
+    AND al.resource_type = 'artifact'
+GROUP By
+    r.repository_id,
+    t.tag_count;`,
+		imagePull: `
+SELECT
+	r.name as repo_name,
+	t.name as tag_name,
+	count(al.id) as pull_count
+FROM
+	audit_log as al
+	JOIN repository as r ON split_part(al.resource, ':', 1) = r.name
+	JOIN tag as t ON t.repository_id = r.repository_id
+	AND al.resource = r.name || ':' || t.name
+WHERE
+	al.operation = 'pull'
+	AND al.resource_type = 'artifact'
+GROUP BY
+	r.name,
+	t.name;`,
+		projectSize: `
+SELECT
+    p.name as project_name,
+    sum(b.size) as size
+FROM
+    blob AS b
+    JOIN artifact_blob AS ab ON ab.digest_blob = b.digest
+    JOIN artifact AS a ON a.digest = ab.digest_af
+    JOIN project AS p ON a.project_id = p.project_id
+GROUP BY
+    p.name;`,
+	},
+}
+
+// readSchemaVersion returns the version Harbor's migrations left in
+// schema_migrations and whether the last migration failed halfway.
+func readSchemaVersion(ctx context.Context, db *sql.DB) (version int64, dirty bool, err error) {
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1;`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, fmt.Errorf("schema_migrations is empty, harbor database not initialized")
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading harbor schema version: %s", err)
+	}
+	return version, dirty, nil
+}
+
+// queriesFor returns the query set for the given schema version.
+func queriesFor(version int64, dirty bool) (*querySet, error) {
+	if dirty {
+		return nil, fmt.Errorf("harbor schema migration %d did not complete (dirty), refusing to query", version)
+	}
+	for i := range querySets {
+		if version >= querySets[i].minVersion && version < querySets[i].maxVersion {
+			return &querySets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported harbor schema version %d, only the schemas of harbor 1.9 to 2.14 (versions %d to %d) are known",
+		version, querySets[0].minVersion, querySets[len(querySets)-1].maxVersion-1)
+}
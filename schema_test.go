@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testDriver is a database driver answering each query with the first of
+// the tables registered for the data source name whose key is part of the
+// query.
+type testDriver struct{}
+
+// testTable is the result of a query, or its error.
+type testTable struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+var testDBs = struct {
+	sync.Mutex
+	tables map[string]map[string]testTable
+}{tables: make(map[string]map[string]testTable)}
+
+func init() {
+	sql.Register("test", testDriver{})
+}
+
+// testDB opens a database answering queries from tables.
+func testDB(t *testing.T, tables map[string]testTable) *sql.DB {
+	t.Helper()
+	testDBs.Lock()
+	name := strconv.Itoa(len(testDBs.tables))
+	testDBs.tables[name] = tables
+	testDBs.Unlock()
+	db, err := sql.Open("test", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	testDBs.Lock()
+	defer testDBs.Unlock()
+	return testConn(testDBs.tables[name]), nil
+}
+
+type testConn map[string]testTable
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	for key, table := range c {
+		if strings.Contains(query, key) {
+			return table, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query %q", query)
+}
+
+func (c testConn) Close() error              { return nil }
+func (c testConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (t testTable) Close() error  { return nil }
+func (t testTable) NumInput() int { return -1 }
+func (t testTable) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (t testTable) Query(args []driver.Value) (driver.Rows, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &testRows{columns: t.columns, rows: t.rows}, nil
+}
+
+type testRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// schemaTable answers the schema_migrations query with version and dirty.
+func schemaTable(version int64, dirty bool) map[string]testTable {
+	return map[string]testTable{"schema_migrations": {
+		columns: []string{"version", "dirty"},
+		rows:    [][]driver.Value{{version, dirty}},
+	}}
+}
+
+func TestReadSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		tables  map[string]testTable
+		version int64
+		dirty   bool
+		err     string
+	}{
+		{name: "clean", tables: schemaTable(100, false), version: 100},
+		{name: "dirty", tables: schemaTable(31, true), version: 31, dirty: true},
+		{
+			name:   "empty",
+			tables: map[string]testTable{"schema_migrations": {columns: []string{"version", "dirty"}}},
+			err:    "not initialized",
+		},
+		{
+			name:   "missing",
+			tables: map[string]testTable{"schema_migrations": {err: errors.New(`relation "schema_migrations" does not exist`)}},
+			err:    "reading harbor schema version",
+		},
+	}
+	for _, test := range tests {
+		db := testDB(t, test.tables)
+		version, dirty, err := readSchemaVersion(context.Background(), db)
+		db.Close()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if version != test.version || dirty != test.dirty {
+			t.Errorf("%s: got %d, %v", test.name, version, dirty)
+		}
+	}
+}
+
+func TestQueriesFor(t *testing.T) {
+	tests := []struct {
+		version int64
+		dirty   bool
+		release string
+		err     string
+	}{
+		{version: 9, err: "unsupported harbor schema version 9"},
+		{version: 10, release: "1.9-1.10"},
+		{version: 15, release: "1.9-1.10"},
+		{version: 29, release: "1.9-1.10"},
+		{version: 30, release: "2.0-2.14"},
+		{version: 100, release: "2.0-2.14"},
+		{version: 179, release: "2.0-2.14"},
+		{version: 180, err: "versions 10 to 179"},
+		{version: 100, dirty: true, err: "migration 100 did not complete"},
+		{version: 5, dirty: true, err: "dirty"},
+	}
+	for _, test := range tests {
+		queries, err := queriesFor(test.version, test.dirty)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%d (dirty %v): error = %v, want %q", test.version, test.dirty, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s", test.version, err)
+			continue
+		}
+		if queries.release != test.release {
+			t.Errorf("%d: got queries of %s, want %s", test.version, queries.release, test.release)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package main
+
+import "fmt"
+
+// statfs is not implemented on this platform.
+func statfs(path string) (total, free uint64, err error) {
+	return 0, 0, fmt.Errorf("reading filesystem usage of %s is not supported on this platform", path)
+}